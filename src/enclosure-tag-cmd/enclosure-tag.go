@@ -25,6 +25,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+
+	"purl.mro.name/recorder/radio/scrape/pbmi"
 )
 
 func main() {
@@ -41,7 +43,7 @@ func main() {
 			continue
 		}
 
-		bc, err := broadcastFromXmlFileName(xmlBroadcastFileNameForMp3EnclosureFileName(ap))
+		bc, err := pbmi.ReadFile(xmlBroadcastFileNameForMp3EnclosureFileName(ap))
 		if nil != err {
 			fmt.Fprintf(os.Stderr, "error %s\n", err)
 			continue
@@ -27,6 +27,8 @@ import (
 	"strconv"
 
 	"github.com/bogem/id3v2"
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
 )
 
 type tagError struct {
@@ -37,7 +39,14 @@ func (e tagError) Error() string {
 	return fmt.Sprintf("%s", e.reason)
 }
 
-func tagMp3File(mp3FilePath string, bc broadcast) error {
+func str(s *string) string {
+	if nil == s {
+		return ""
+	}
+	return *s
+}
+
+func tagMp3File(mp3FilePath string, bc scrape.Broadcast) error {
 	m := regexp.MustCompile(`([^/]+)/(\d{4})/\d{2}/\d{2}/\d{4}(?:\s.*)?\.mp3$`).FindStringSubmatch(mp3FilePath)
 	station := m[1]
 	if "" == station {
@@ -53,20 +62,20 @@ func tagMp3File(mp3FilePath string, bc broadcast) error {
 	tag.DeleteAllFrames()
 	tag.SetVersion(4)
 	tag.SetArtist("Station " + station)
-	tag.SetTitle(bc.title)
-	tag.SetAlbum(bc.titleSeries)
+	tag.SetTitle(bc.Title)
+	tag.SetAlbum(str(bc.TitleSeries))
 	tag.SetGenre("Radio")
-	tag.SetYear(strconv.Itoa(bc.formatTimeStart.Year()))
+	tag.SetYear(strconv.Itoa(bc.Time.Year()))
 
-	txt := bc.identifier + "\n"
-	txt += bc.source.String() + "\n\n"
-	if "" != bc.titleSeries {
-		txt += bc.titleSeries + "\n\n"
+	txt := pbmi.Identifier(bc) + "\n"
+	txt += bc.Source.String() + "\n\n"
+	if "" != str(bc.TitleSeries) {
+		txt += *bc.TitleSeries + "\n\n"
 	}
-	if "" != bc.titleEpisode {
-		txt += bc.titleEpisode + "\n\n"
+	if "" != str(bc.TitleEpisode) {
+		txt += *bc.TitleEpisode + "\n\n"
 	}
-	txt += bc.description
+	txt += str(bc.Description)
 
 	tag.AddCommentFrame(id3v2.CommentFrame{
 		Encoding: id3v2.EncodingUTF8,
@@ -81,8 +90,8 @@ func tagMp3File(mp3FilePath string, bc broadcast) error {
 		Lyrics: txt,
 	})
 
-	if "" != bc.image.String() {
-		resp, err := http.Get(bc.image.String())
+	if nil != bc.Image && "" != bc.Image.String() {
+		resp, err := http.Get(bc.Image.String())
 		if err != nil {
 			return err
 		}
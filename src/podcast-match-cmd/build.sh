@@ -0,0 +1,38 @@
+#!/bin/sh
+# https://golang.org/doc/install/source#environment
+#
+
+cd "$(dirname "${0}")"
+# $ uname -s -m
+# Darwin x86_64
+# Linux x86_64
+# Linux armv6l
+
+PROG_NAME="podcast-match"
+VERSION="0.0.1"
+
+rm "${PROG_NAME}"-*-"${VERSION}" 2>/dev/null
+
+go get -u "github.com/stretchr/testify"
+
+CWD="$(pwd)"
+cd ..
+for dir in "${PROG_NAME}-cmd" podcast
+do
+  cd "${CWD}/../${dir}"
+  go fmt && go test ; \
+  {
+    echo "<html><head>"
+    echo "<meta http-equiv='Content-type' content='text/html; charset=utf-8' />"
+    echo "<title>go package 'purl.mro.name/recorder/radio/${dir}'</title>"
+    echo "</head><body>"
+    godoc -html "purl.mro.name/recorder/radio/${dir}"
+  } | tidy -utf8 -asxhtml -indent -wrap 100 -quiet - 2>/dev/null > index.html
+done
+cd "${CWD}"
+
+# http://dave.cheney.net/2015/08/22/cross-compilation-with-go-1-5
+env GOOS=linux GOARCH=arm GOARM=6 go build -o "${PROG_NAME}-linux-arm-${VERSION}"
+env GOOS=linux GOARCH=amd64 go build -o "${PROG_NAME}-linux-amd64-${VERSION}"
+env GOOS=linux GOARCH=386 GO386=387 go build -o "${PROG_NAME}-linux-386-${VERSION}" # https://github.com/golang/go/issues/11631
+env GOOS=darwin GOARCH=amd64 go build -o "${PROG_NAME}-darwin-amd64-${VERSION}"
@@ -0,0 +1,76 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"purl.mro.name/recorder/radio/podcast"
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+func main() {
+	flag.Usage = commandHelp
+	dir := flag.String("podcasts", "podcasts", "directory containing <id>/app/podcast.json")
+	flag.Parse()
+
+	pcs, err := podcast.LoadAll(*dir)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+		os.Exit(1)
+	}
+
+	var bcs []scrape.Broadcast
+	if 0 == flag.NArg() {
+		// JSON Lines as written by 'scrape -format json'
+		if bcs, err = scrape.ReadBroadcastsJSON(os.Stdin); nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, xmlFileName := range flag.Args() {
+		bc, err := pbmi.ReadFile(xmlFileName)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			continue
+		}
+		bcs = append(bcs, bc)
+	}
+
+	for _, bc := range bcs {
+		fmt.Printf("checkng %s\n", pbmi.Identifier(bc))
+		for _, pc := range podcast.Match(pcs, bc) {
+			fmt.Printf("matched %s\n", pc.Identifier)
+		}
+	}
+}
+
+func commandHelp() {
+	program := os.Args[0]
+	fmt.Printf("Usage: %s [-podcasts dir] [stations/b2/2016/08/25/1805\\ Bayern\\ 2-radioMusik.xml ...]\n", program)
+	fmt.Printf("\n")
+	fmt.Printf("reports the podcasts each broadcast would join according to podcasts/*/app/podcast.json.\n")
+	fmt.Printf("Reads broadcast xml files or, without arguments, JSON Lines from stdin.\n")
+	fmt.Printf("\n")
+	flag.PrintDefaults()
+}
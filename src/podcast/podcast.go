@@ -0,0 +1,287 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Decide podcast membership of broadcasts by declarative rules, the
+// successor of the lua 'match = function(meta)' in podcasts/*/app/podcast.cfg
+//
+// Each podcast has a rule file podcasts/<id>/app/podcast.json like
+//
+//   {
+//     "title": "Krimi",
+//     "match": [
+//       { "text": "(?i)wolf\\s+haas" },
+//       { "title": "(?i)^radiokrimi", "stations": ["b2"], "weekdays": ["Wed"], "until": "21:00" }
+//     ],
+//     "exclude": [
+//       { "description": "Jan Weiler" }
+//     ]
+//   }
+//
+// A broadcast joins a podcast if any 'match' rule and no 'exclude' rule
+// matches. Within a rule all given conditions must hold.
+//
+// import "purl.mro.name/recorder/radio/podcast"
+
+package podcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+type Podcast struct {
+	Identifier     string `json:"-"`
+	Title          string `json:"title"`
+	Subtitle       string `json:"subtitle"`
+	EpisodesToKeep int    `json:"episodes_to_keep"`
+	Match          []Rule `json:"match"`
+	Exclude        []Rule `json:"exclude"`
+}
+
+// All conditions present must hold.
+type Rule struct {
+	Title       *Regexp   `json:"title"`       // DC.title
+	Description *Regexp   `json:"description"` // DC.description
+	Series      *Regexp   `json:"series"`      // DC.title.series
+	Subject     *Regexp   `json:"subject"`     // DC.subject
+	Text        *Regexp   `json:"text"`        // either title or description
+	Stations    []string  `json:"stations"`    // allow-list of station identifiers
+	Weekdays    []Weekday `json:"weekdays"`    // start day (station local time)
+	From        *Clock    `json:"from"`        // start at or after (station local time)
+	Until       *Clock    `json:"until"`       // start before (station local time)
+	MinDuration *Duration `json:"min_duration"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// JSON value types
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); nil != err {
+		return
+	}
+	r.Regexp, err = regexp.Compile(s)
+	return
+}
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// 'Mon', 'Tue', ...
+type Weekday time.Weekday
+
+func (d *Weekday) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); nil != err {
+		return
+	}
+	wd, ok := weekdays[s]
+	if !ok {
+		return fmt.Errorf("unknown weekday '%s'", s)
+	}
+	*d = Weekday(wd)
+	return
+}
+
+// Time of day 'hh:mm', stored as duration since midnight.
+type Clock time.Duration
+
+func (c *Clock) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); nil != err {
+		return
+	}
+	t, err := time.Parse("15:04", s)
+	if nil != err {
+		return
+	}
+	*c = Clock(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+	return
+}
+
+// Go duration syntax, e.g. '25m' or '1h30m'
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); nil != err {
+		return
+	}
+	dt, err := time.ParseDuration(s)
+	*d = Duration(dt)
+	return
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Matching
+//////////////////////////////////////////////////////////////////////////////////////////
+
+func str(s *string) string {
+	if nil == s {
+		return ""
+	}
+	return *s
+}
+
+func strURL(u *url.URL) string {
+	if nil == u {
+		return ""
+	}
+	return u.String()
+}
+
+func (r Rule) Matches(bc scrape.Broadcast) bool {
+	rx := func(re *Regexp, s string) bool {
+		return nil == re || re.MatchString(s)
+	}
+	if !rx(r.Title, bc.Title) ||
+		!rx(r.Description, str(bc.Description)) ||
+		!rx(r.Series, str(bc.TitleSeries)) ||
+		!rx(r.Subject, strURL(bc.Subject)) {
+		return false
+	}
+	if nil != r.Text && !r.Text.MatchString(bc.Title) && !r.Text.MatchString(str(bc.Description)) {
+		return false
+	}
+	if 0 < len(r.Stations) {
+		ok := false
+		for _, s := range r.Stations {
+			ok = ok || s == bc.Station.Identifier
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	t := bc.Time
+	if nil != bc.Station.TimeZone {
+		t = t.In(bc.Station.TimeZone)
+	}
+	if 0 < len(r.Weekdays) {
+		ok := false
+		for _, wd := range r.Weekdays {
+			ok = ok || time.Weekday(wd) == t.Weekday()
+		}
+		if !ok {
+			return false
+		}
+	}
+	tod := Clock(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)
+	switch {
+	case nil != r.From && nil != r.Until && *r.Until < *r.From:
+		// window across midnight
+		if tod < *r.From && *r.Until <= tod {
+			return false
+		}
+	default:
+		if nil != r.From && tod < *r.From {
+			return false
+		}
+		if nil != r.Until && *r.Until <= tod {
+			return false
+		}
+	}
+
+	if nil != r.MinDuration {
+		if nil == bc.DtEnd || bc.DtEnd.Sub(bc.Time) < time.Duration(*r.MinDuration) {
+			return false
+		}
+	}
+	return true
+}
+
+func (pc Podcast) Matches(bc scrape.Broadcast) bool {
+	for _, r := range pc.Exclude {
+		if r.Matches(bc) {
+			return false
+		}
+	}
+	for _, r := range pc.Match {
+		if r.Matches(bc) {
+			return true
+		}
+	}
+	return false
+}
+
+// All podcasts the broadcast would join.
+func Match(pcs []Podcast, bc scrape.Broadcast) (ret []Podcast) {
+	for _, pc := range pcs {
+		if pc.Matches(bc) {
+			ret = append(ret, pc)
+		}
+	}
+	return
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Rule files
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// Read one podcast rule file. The Identifier is taken from the path
+// podcasts/<id>/app/podcast.json
+func Load(file string) (pc Podcast, err error) {
+	f, err := os.Open(file)
+	if nil != err {
+		return
+	}
+	defer f.Close()
+	if err = json.NewDecoder(f).Decode(&pc); nil != err {
+		return pc, fmt.Errorf("%s: %s", file, err)
+	}
+	pc.Identifier = filepath.Base(filepath.Dir(filepath.Dir(file)))
+	return
+}
+
+// Read all rule files podcasts/*/app/podcast.json below dir, sorted by Identifier.
+func LoadAll(dir string) (ret []Podcast, err error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "app", "podcast.json"))
+	if nil != err {
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		pc, err := Load(file)
+		if nil != err {
+			return ret, err
+		}
+		ret = append(ret, pc)
+	}
+	return
+}
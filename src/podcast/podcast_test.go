@@ -0,0 +1,156 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/podcast"
+//
+package podcast
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+func bc(station string, start time.Time, minutes int, title string, description string) scrape.Broadcast {
+	ret := scrape.Broadcast{}
+	ret.Station = scrape.Station{Identifier: station, TimeZone: berlin}
+	ret.Time = start
+	ret.Title = title
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	ret.DtEnd = &end
+	ret.Description = &description
+	return ret
+}
+
+func rule(t *testing.T, src string) (r Rule) {
+	err := json.Unmarshal([]byte(src), &r)
+	assert.Nil(t, err, "ouch")
+	return
+}
+
+func TestRuleRegexp(t *testing.T) {
+	b := bc("b2", time.Date(2015, 10, 21, 20, 5, 0, 0, berlin), 55, "Hörspiel", "Von Wolf  Haas")
+	assert.True(t, rule(t, `{}`).Matches(b), "empty rule matches all")
+	assert.True(t, rule(t, `{"title":"(?i)^hörspiel$"}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"title":"(?i)krimi"}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"description":"Wolf\\s+Haas"}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"text":"(?i)wolf\\s+haas"}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"text":"(?i)hörspiel"}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"series":"."}`).Matches(b), "no series")
+	assert.False(t, rule(t, `{"subject":"."}`).Matches(b), "no subject")
+
+	series := "Hörspiel am Mittwoch"
+	b.TitleSeries = &series
+	b.Subject = scrape.MustParseURL("http://www.br.de/radio/bayern2/sendungen/hoerspiel-pool/index.html")
+	assert.True(t, rule(t, `{"series":"Mittwoch$"}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"subject":"/hoerspiel-pool/"}`).Matches(b), "ouch")
+
+	var r Rule
+	err := json.Unmarshal([]byte(`{"title":"("}`), &r)
+	assert.NotNil(t, err, "invalid regexp")
+}
+
+func TestRuleStations(t *testing.T) {
+	b := bc("b2", time.Date(2015, 10, 21, 20, 5, 0, 0, berlin), 55, "Hörspiel", "")
+	assert.True(t, rule(t, `{"stations":["b1","b2"]}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"stations":["dlf"]}`).Matches(b), "ouch")
+}
+
+func TestRuleWeekdaysAndTime(t *testing.T) {
+	// Wednesday
+	b := bc("b2", time.Date(2015, 10, 21, 20, 5, 0, 0, berlin), 55, "Radiokrimi", "")
+	assert.True(t, rule(t, `{"weekdays":["Mon","Wed"]}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"weekdays":["Thu"]}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"from":"20:05","until":"21:00"}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"until":"20:05"}`).Matches(b), "until is exclusive")
+	assert.False(t, rule(t, `{"from":"20:06"}`).Matches(b), "ouch")
+	assert.True(t, rule(t, `{"from":"20:00","until":"02:00"}`).Matches(b), "across midnight")
+	assert.False(t, rule(t, `{"from":"22:00","until":"06:00"}`).Matches(b), "across midnight")
+
+	// station local time counts, not the zone of the time value
+	b.Time = b.Time.UTC()
+	assert.True(t, rule(t, `{"from":"20:00","until":"21:00"}`).Matches(b), "ouch")
+
+	var r Rule
+	err := json.Unmarshal([]byte(`{"weekdays":["Mittwoch"]}`), &r)
+	assert.Equal(t, "unknown weekday 'Mittwoch'", err.Error(), "ouch")
+}
+
+func TestRuleMinDuration(t *testing.T) {
+	b := bc("dlf", time.Date(2015, 10, 25, 0, 5, 0, 0, berlin), 55, "Mitternachtskrimi", "")
+	assert.True(t, rule(t, `{"min_duration":"55m"}`).Matches(b), "ouch")
+	assert.False(t, rule(t, `{"min_duration":"1h"}`).Matches(b), "ouch")
+	b.DtEnd = nil
+	assert.False(t, rule(t, `{"min_duration":"1m"}`).Matches(b), "unknown duration")
+}
+
+func TestPodcastExclude(t *testing.T) {
+	var pc Podcast
+	err := json.Unmarshal([]byte(`{"match":[{"title":"(?i)nachtmix"}],"exclude":[{"description":"Jan Weiler"}]}`), &pc)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, pc.Matches(bc("b2", time.Date(2015, 10, 25, 23, 5, 0, 0, berlin), 55, "Nachtmix", "Musik")), "ouch")
+	assert.False(t, pc.Matches(bc("b2", time.Date(2015, 10, 25, 23, 5, 0, 0, berlin), 55, "Nachtmix", "Mit Jan Weiler")), "ouch")
+	assert.False(t, Podcast{}.Matches(bc("b2", time.Date(2015, 10, 25, 23, 5, 0, 0, berlin), 55, "Nachtmix", "")), "no rules, no match")
+}
+
+// the real rules from htdocs/podcasts
+func TestLoadAllHtdocs(t *testing.T) {
+	pcs, err := LoadAll("../../htdocs/podcasts")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 11, len(pcs), "ouch")
+	assert.Equal(t, "ad_hoc", pcs[0].Identifier, "ouch")
+	assert.Equal(t, "AdHoc", pcs[0].Title, "ouch")
+	assert.Equal(t, 50, pcs[0].EpisodesToKeep, "ouch")
+
+	ids := func(b scrape.Broadcast) (ret []string) {
+		for _, pc := range Match(pcs, b) {
+			ret = append(ret, pc.Identifier)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"krimi"}, ids(bc("b2", time.Date(2015, 10, 21, 20, 5, 0, 0, berlin), 55, "Radiokrimi", "Die dritte Person")), "ouch")
+	assert.Nil(t, ids(bc("b2", time.Date(2015, 10, 21, 21, 5, 0, 0, berlin), 55, "Radiokrimi", "Wiederholung")), "too late")
+	assert.Equal(t, []string{"krimi"}, ids(bc("b2", time.Date(2015, 4, 5, 21, 0, 0, 0, berlin), 55, "Hörspiel", "Komm, süßer Tod\nVon Wolf Haas")), "ouch")
+	assert.Equal(t, []string{"mitternachtskrimi"}, ids(bc("dlf", time.Date(2015, 10, 25, 0, 5, 0, 0, berlin), 55, "Mitternachtskrimi", "")), "ouch")
+	assert.Equal(t, []string{"pumuckl"}, ids(bc("b1", time.Date(2015, 10, 25, 7, 5, 0, 0, berlin), 25, "Meister Eder", "Von Ellis Kaut\nMit Hans Clarin")), "ouch")
+	assert.Equal(t, []string{"radiowelt"}, ids(bc("b2", time.Date(2015, 10, 21, 7, 0, 0, 0, berlin), 60, "radioWelt am Morgen", "")), "ouch")
+	assert.Nil(t, ids(bc("b2", time.Date(2015, 10, 21, 17, 0, 0, 0, berlin), 60, "radioWelt am Abend", "")), "afternoon")
+	assert.Equal(t, []string{"zuendfunk"}, ids(bc("b2", time.Date(2015, 10, 21, 19, 5, 0, 0, berlin), 55, "ZÜNDFUNK", "")), "ouch")
+	assert.Nil(t, ids(bc("b2", time.Date(2015, 10, 21, 23, 5, 0, 0, berlin), 55, "Nachtmix", "Jan Weiler liest")), "excluded")
+	assert.Equal(t, []string{"kulturwelt"}, ids(bc("b2", time.Date(2015, 10, 21, 8, 30, 0, 0, berlin), 30, "kulturWelt", "")), "ouch")
+	assert.Nil(t, ids(bc("b2", time.Date(2015, 10, 21, 22, 30, 0, 0, berlin), 30, "kulturWelt", "Wiederholung, gekürzt\naus der Sendung von 8.30 Uhr")), "excluded across lines")
+
+	b := bc("b2", time.Date(2016, 8, 25, 18, 5, 0, 0, berlin), 25, "Bayern 2-radioMusik", "")
+	b.Subject = scrape.MustParseURL("http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/index.html")
+	assert.Equal(t, []string{"radio_mitschnitt"}, ids(b), "ouch")
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := Load("testdata/nonexistent/app/podcast.json")
+	assert.NotNil(t, err, "ouch")
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+
+	"purl.mro.name/recorder/radio/rip"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+func main() {
+	flag.Usage = commandHelp
+	head := flag.Duration("head", 3*time.Second, "start recording that early")
+	tail := flag.Duration("tail", 15*time.Second, "stop recording that late")
+	retries := flag.Int("retries", rip.Default.Retries, "consecutive reconnects before giving up")
+	flag.Parse()
+	if 1 != flag.NArg() {
+		commandHelp()
+		os.Exit(2)
+	}
+
+	id := identifierForFileName(flag.Arg(0))
+	if "" == id {
+		fmt.Fprintf(os.Stderr, "error not a broadcast '%s'\n", flag.Arg(0))
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	if err := ripBroadcast(ctx, id, *head, *tail, *retries); nil != err {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func commandHelp() {
+	program := os.Args[0]
+	fmt.Printf("Usage: %s [options] enclosures/b2/2013/01/20/1405\\ musikWelt\n", program)
+	fmt.Printf("\n")
+	fmt.Printf("records the broadcast's time window from the station's stream_url into enclosures/<id>.mp3\n")
+	fmt.Printf("\n")
+	flag.PrintDefaults()
+}
+
+// Strip 'enclosures/' or 'stations/' prefix and extension.
+func identifierForFileName(fileName string) string {
+	rx := regexp.MustCompile("([^/]+/\\d{4}/\\d{2}/\\d{2}/\\d{4}(?: [^/]+?)?)(?:\\.(?:xml|json|mp3|pending|ripping|failed))?$")
+	m := rx.FindStringSubmatch(filepath.ToSlash(fileName))
+	if nil == m {
+		return ""
+	}
+	return m[1]
+}
+
+func ripBroadcast(ctx context.Context, id string, head, tail time.Duration, retries int) (err error) {
+	bc, err := pbmi.ReadFile(filepath.Join("stations", filepath.FromSlash(id)+".xml"))
+	if nil != err {
+		return
+	}
+	if nil == bc.DtEnd {
+		return fmt.Errorf("missing DC.format.timeend in %s", id)
+	}
+	stream, err := rip.StreamURL(filepath.Join("stations", bc.Station.Identifier, "app", "station.cfg"))
+	if nil != err {
+		return
+	}
+
+	base := filepath.Join("enclosures", filepath.FromSlash(id))
+	if err = os.MkdirAll(filepath.Dir(base), 0755); nil != err {
+		return
+	}
+	// append, a rip resumed after a restart must not wipe what's there already
+	f, err := os.OpenFile(base+".ripping", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if nil != err {
+		return
+	}
+	from, until := bc.Time.Add(-head), bc.DtEnd.Add(tail)
+	fmt.Fprintf(os.Stderr, "starting rip %s until %s\n", id, until.Format("2006-01-02 15:04:05"))
+
+	r := rip.Default
+	r.Retries = retries
+	rep, err := r.Rip(ctx, stream, from, until, f)
+	if e := f.Close(); nil == err {
+		err = e
+	}
+	fmt.Fprintf(os.Stderr, "ripped  %s %s\n", id, rep)
+	for _, g := range rep.Gaps {
+		fmt.Fprintf(os.Stderr, "gap     %s - %s (%s)\n", g.From.Format(time.RFC3339), g.To.Format(time.RFC3339), g.To.Sub(g.From))
+	}
+	if context.Canceled == err {
+		// interrupted, e.g. the scheduler shutting down, it resumes later
+		return
+	}
+	if nil != err {
+		// keep the partial .ripping, mark as failed like Enclosure:unschedule('failed')
+		os.Remove(base + ".pending")
+		ioutil.WriteFile(base+".failed", []byte{}, 0644)
+		return
+	}
+	os.Remove(base + ".pending")
+	return os.Rename(base+".ripping", base+".mp3")
+}
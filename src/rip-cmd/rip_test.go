@@ -0,0 +1,102 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+func TestIdentifierForFileName(t *testing.T) {
+	assert.Equal(t, "b2/2013/01/20/1405 musikWelt", identifierForFileName("enclosures/b2/2013/01/20/1405 musikWelt"), "ouch")
+	assert.Equal(t, "b2/2013/01/20/1405 musikWelt", identifierForFileName("/srv/htdocs/enclosures/b2/2013/01/20/1405 musikWelt.pending"), "ouch")
+	assert.Equal(t, "b2/2013/01/20/1405 musikWelt", identifierForFileName("stations/b2/2013/01/20/1405 musikWelt.xml"), "ouch")
+	assert.Equal(t, "dlf/2015/10/25/0005 Mitternachtskrimi. Teil 2", identifierForFileName("enclosures/dlf/2015/10/25/0005 Mitternachtskrimi. Teil 2"), "ouch")
+	assert.Equal(t, "", identifierForFileName("enclosures/b2/2013/01/20"), "ouch")
+}
+
+// A rip interrupted e.g. by the scheduler shutting down appends and leaves
+// the markers, the scheduler resumes it later.
+func TestRipBroadcastResume(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		for {
+			w.Write(bytes.Repeat([]byte{0xff}, 100))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "rip-cmd")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	assert.Nil(t, os.Chdir(dir), "ouch")
+
+	bc := scrape.Broadcast{}
+	bc.Station.Identifier = "b2"
+	bc.Time, bc.Title = time.Now().Truncate(time.Minute), "Nachtmix"
+	end := bc.Time.Add(time.Hour)
+	bc.DtEnd = &end
+	id := pbmi.Identifier(bc)
+	var xml bytes.Buffer
+	assert.Nil(t, pbmi.Write(&xml, bc), "ouch")
+	os.MkdirAll(filepath.Join("stations", "b2", "app"), 0755)
+	os.MkdirAll(filepath.Dir(filepath.Join("stations", id)), 0755)
+	os.MkdirAll(filepath.Dir(filepath.Join("enclosures", id)), 0755)
+	ioutil.WriteFile(filepath.Join("stations", "b2", "app", "station.cfg"), []byte("{\n\tstream_url = '"+srv.URL+"/stream.mp3',\n}"), 0644)
+	ioutil.WriteFile(filepath.Join("stations", id+".xml"), xml.Bytes(), 0644)
+	base := filepath.Join("enclosures", id)
+	ioutil.WriteFile(base+".ripping", []byte("before the restart"), 0644)
+	ioutil.WriteFile(base+".pending", []byte{}, 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	assert.Equal(t, context.Canceled, ripBroadcast(ctx, id, time.Minute, 0, 1), "ouch")
+
+	data, err := ioutil.ReadFile(base + ".ripping")
+	assert.Nil(t, err, "ouch")
+	assert.True(t, bytes.HasPrefix(data, []byte("before the restart")), "appended")
+	assert.True(t, len(data) > len("before the restart"), "appended")
+	_, err = os.Stat(base + ".pending")
+	assert.Nil(t, err, "kept")
+	_, err = os.Stat(base + ".failed")
+	assert.True(t, os.IsNotExist(err), "not failed")
+}
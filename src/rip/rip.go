@@ -0,0 +1,268 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Record a time window of an mp3 http stream, the successor of
+// htdocs/app/enclosure-rip.lua and streamripper.
+//
+// import "purl.mro.name/recorder/radio/rip"
+
+package rip
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Same as enclosure-rip.lua passes to streamripper.
+var UserAgent = "iTunes/11.0.1 (Macintosh; OS X 10.7.5) AppleWebKit/534.57.7"
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// station.cfg and playlists
+//////////////////////////////////////////////////////////////////////////////////////////
+
+var rxStreamURL = regexp.MustCompile(`(?m)^\s*stream_url\s*=\s*'([^']*)'`)
+
+// Read 'stream_url' from a stations/<id>/app/station.cfg lua table.
+func StreamURL(stationCfg string) (ret *url.URL, err error) {
+	data, err := ioutil.ReadFile(stationCfg)
+	if nil != err {
+		return
+	}
+	m := rxStreamURL.FindSubmatch(data)
+	if nil == m {
+		return nil, fmt.Errorf("%s: no stream_url", stationCfg)
+	}
+	return url.Parse(string(m[1]))
+}
+
+func get(ctx context.Context, client *http.Client, u *url.URL) (resp *http.Response, err error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if nil != err {
+		return
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if resp, err = client.Do(req.WithContext(ctx)); nil != err {
+		return
+	}
+	if http.StatusOK != resp.StatusCode {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s", resp.Status, u)
+	}
+	return
+}
+
+func isPlaylist(u *url.URL, contentType string) (m3u bool, pls bool) {
+	p := strings.ToLower(u.Path)
+	ct := strings.ToLower(contentType)
+	m3u = strings.HasSuffix(p, ".m3u") || strings.Contains(ct, "mpegurl")
+	pls = strings.HasSuffix(p, ".pls") || strings.Contains(ct, "scpls")
+	return
+}
+
+// First entry of a m3u or pls playlist.
+func parsePlaylist(base *url.URL, r io.Reader, pls bool) (*url.URL, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if pls {
+			kv := strings.SplitN(line, "=", 2)
+			if 2 != len(kv) || !strings.HasPrefix(strings.ToLower(kv[0]), "file") {
+				continue
+			}
+			line = strings.TrimSpace(kv[1])
+		} else if "" == line || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if nil != err {
+			return nil, err
+		}
+		return base.ResolveReference(u), nil
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, err
+	}
+	return nil, errors.New("empty playlist " + base.String())
+}
+
+// Follow .m3u and .pls playlists to the actual stream.
+func Resolve(ctx context.Context, client *http.Client, u *url.URL) (*url.URL, error) {
+	for i := 0; i < 5; i++ {
+		if m3u, pls := isPlaylist(u, ""); !m3u && !pls {
+			return u, nil
+		}
+		resp, err := get(ctx, client, u)
+		if nil != err {
+			return nil, err
+		}
+		_, pls := isPlaylist(u, resp.Header.Get("Content-Type"))
+		next, err := parsePlaylist(u, resp.Body, pls)
+		resp.Body.Close()
+		if nil != err {
+			return nil, err
+		}
+		u = next
+	}
+	return nil, errors.New("too many nested playlists " + u.String())
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Ripping
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// A period without data, e.g. between connection drop and reconnect.
+type Gap struct {
+	From time.Time
+	To   time.Time
+}
+
+type Report struct {
+	Bytes    int64 // written
+	Connects int
+	Gaps     []Gap
+}
+
+func (r Report) GapDuration() (ret time.Duration) {
+	for _, g := range r.Gaps {
+		ret += g.To.Sub(g.From)
+	}
+	return
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d bytes, %d connects, %d gaps (%s)", r.Bytes, r.Connects, len(r.Gaps), r.GapDuration())
+}
+
+type Ripper struct {
+	Client      *http.Client
+	Retries     int           // consecutive failed (re)connects before giving up
+	Backoff     time.Duration // pause before reconnecting
+	IdleTimeout time.Duration // reconnect if a connection stays silent that long, 0: never
+}
+
+var Default = Ripper{Client: http.DefaultClient, Retries: 10, Backoff: 2 * time.Second, IdleTimeout: 15 * time.Second}
+
+// Write the stream bytes received in [from,until) to w. Reconnects on drop.
+func (r Ripper) Rip(ctx context.Context, stream *url.URL, from, until time.Time, w io.Writer) (rep Report, err error) {
+	ctx, cancel := context.WithDeadline(ctx, until)
+	defer cancel()
+
+	src, err := Resolve(ctx, r.Client, stream)
+	if nil != err {
+		return
+	}
+
+	var lost time.Time // begin of the current gap
+	failures := 0
+	buf := make([]byte, 32*1024)
+	for {
+		// a stalled connection blocks Read forever, so hang up if it stays silent.
+		conn, hangup := context.WithCancel(ctx)
+		var stalled int32
+		var watchdog *time.Timer
+		if 0 < r.IdleTimeout {
+			watchdog = time.AfterFunc(r.IdleTimeout, func() { atomic.StoreInt32(&stalled, 1); hangup() })
+		}
+		resp, e := get(conn, r.Client, src)
+		lastData := time.Now()
+		if nil == e {
+			rep.Connects++
+			for {
+				n, e1 := resp.Body.Read(buf)
+				now := time.Now()
+				if 0 < n {
+					lastData = now
+					if nil != watchdog {
+						watchdog.Reset(r.IdleTimeout)
+					}
+				}
+				if 0 < n && !now.Before(from) {
+					if !lost.IsZero() {
+						if lost.Before(from) {
+							lost = from
+						}
+						rep.Gaps = append(rep.Gaps, Gap{From: lost, To: now})
+						lost = time.Time{}
+					}
+					failures = 0
+					if now.Before(until) {
+						m, e2 := w.Write(buf[:n])
+						rep.Bytes += int64(m)
+						if nil != e2 {
+							resp.Body.Close()
+							hangup()
+							return rep, e2
+						}
+					}
+				}
+				if nil != e1 {
+					e = e1
+					break
+				}
+			}
+			resp.Body.Close()
+		}
+		if nil != watchdog {
+			watchdog.Stop()
+		}
+		hangup()
+		if 1 == atomic.LoadInt32(&stalled) && nil == ctx.Err() {
+			e = fmt.Errorf("no data for %s", r.IdleTimeout)
+			if lost.IsZero() {
+				lost = lastData // the gap began with the silence
+			}
+		}
+		if nil != ctx.Err() {
+			// window over or cancelled
+			if !lost.IsZero() || 0 == rep.Bytes {
+				if lost.Before(from) {
+					lost = from
+				}
+				if lost.Before(until) {
+					rep.Gaps = append(rep.Gaps, Gap{From: lost, To: until})
+				}
+			}
+			if context.DeadlineExceeded == ctx.Err() {
+				return rep, nil
+			}
+			return rep, ctx.Err()
+		}
+		if lost.IsZero() {
+			lost = time.Now()
+		}
+		failures++
+		if failures > r.Retries {
+			return rep, fmt.Errorf("giving up after %d retries: %s", r.Retries, e)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.Backoff):
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/rip"
+//
+package rip
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A stand-in for a radio stream: 100 bytes every 10ms, playlists pointing to it.
+// Each connection ends after dropAfter chunks (0: never).
+func streamServer(dropAfter int, connects *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		fmt.Fprintf(w, "#EXTM3U\n\n#EXTINF:-1,Stand-in\nhttp://%s/stream.mp3\n", r.Host)
+	})
+	mux.HandleFunc("/live.pls", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[playlist]\nNumberOfEntries=1\nFile1=/stream.mp3\nTitle1=Stand-in\n")
+	})
+	mux.HandleFunc("/stream.mp3", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(connects, 1)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		chunk := bytes.Repeat([]byte{0xff}, 100)
+		for i := 0; 0 == dropAfter || i < dropAfter; i++ {
+			if _, err := w.Write(chunk); nil != err {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestStreamURL(t *testing.T) {
+	u, err := StreamURL("testdata/station.cfg")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "http://streams.br-online.de/bayern2_2.m3u", u.String(), "ouch")
+
+	_, err = StreamURL("testdata/station-no-stream.cfg")
+	assert.Equal(t, "testdata/station-no-stream.cfg: no stream_url", err.Error(), "ouch")
+}
+
+func TestResolve(t *testing.T) {
+	var connects int32
+	srv := streamServer(1, &connects)
+	defer srv.Close()
+
+	for _, p := range []string{"/live.m3u", "/live.pls", "/stream.mp3"} {
+		u, err := Resolve(context.Background(), http.DefaultClient, mustParse(srv.URL+p))
+		assert.Nil(t, err, "ouch")
+		assert.Equal(t, srv.URL+"/stream.mp3", u.String(), p)
+	}
+	assert.Equal(t, int32(0), connects, "the stream itself isn't touched")
+
+	_, err := Resolve(context.Background(), http.DefaultClient, mustParse(srv.URL+"/missing.m3u"))
+	assert.True(t, strings.HasPrefix(err.Error(), "404 Not Found "), "ouch")
+}
+
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if nil != err {
+		panic(err)
+	}
+	return u
+}
+
+func TestRipWindow(t *testing.T) {
+	var connects int32
+	srv := streamServer(0, &connects)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	from := time.Now().Add(100 * time.Millisecond)
+	until := from.Add(300 * time.Millisecond)
+	rep, err := Default.Rip(context.Background(), mustParse(srv.URL+"/live.m3u"), from, until, &buf)
+	assert.Nil(t, err, "ouch")
+	assert.False(t, time.Now().Before(until), "runs until the end of the window")
+	assert.Equal(t, int64(buf.Len()), rep.Bytes, "ouch")
+	// ~30 chunks inside the window, ~10 before are dropped
+	assert.True(t, 1000 < rep.Bytes && rep.Bytes < 4000, fmt.Sprintf("bytes %d", rep.Bytes))
+	assert.Equal(t, 1, rep.Connects, "ouch")
+	assert.Equal(t, 0, len(rep.Gaps), "ouch")
+}
+
+func TestRipReconnect(t *testing.T) {
+	var connects int32
+	srv := streamServer(5, &connects)
+	defer srv.Close()
+
+	r := Ripper{Client: http.DefaultClient, Retries: 3, Backoff: 30 * time.Millisecond}
+	var buf bytes.Buffer
+	from := time.Now()
+	until := from.Add(400 * time.Millisecond)
+	rep, err := r.Rip(context.Background(), mustParse(srv.URL+"/live.pls"), from, until, &buf)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, 2 <= rep.Connects, fmt.Sprintf("connects %d", rep.Connects))
+	assert.Equal(t, int(connects), rep.Connects, "ouch")
+	assert.True(t, 1 <= len(rep.Gaps), "ouch")
+	for _, g := range rep.Gaps {
+		assert.True(t, g.From.Before(g.To), "ouch")
+		assert.False(t, g.From.Before(from), "inside window")
+		assert.False(t, until.Before(g.To), "inside window")
+	}
+	assert.True(t, 30*time.Millisecond <= rep.GapDuration(), rep.String())
+	assert.Equal(t, int64(buf.Len()), rep.Bytes, "ouch")
+}
+
+func TestRipStall(t *testing.T) {
+	var connects int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connects, 1)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		for i := 0; i < 3; i++ {
+			w.Write(bytes.Repeat([]byte{0xff}, 100))
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		// keep the connection open but silent
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	r := Ripper{Client: http.DefaultClient, Retries: 3, Backoff: 10 * time.Millisecond, IdleTimeout: 50 * time.Millisecond}
+	var buf bytes.Buffer
+	from := time.Now()
+	until := from.Add(400 * time.Millisecond)
+	rep, err := r.Rip(context.Background(), mustParse(srv.URL+"/stream.mp3"), from, until, &buf)
+	assert.Nil(t, err, "ouch")
+	assert.False(t, time.Now().Before(until), "runs until the end of the window")
+	assert.True(t, 2 <= rep.Connects, fmt.Sprintf("connects %d", rep.Connects))
+	assert.Equal(t, int(connects), rep.Connects, "ouch")
+	assert.True(t, 1 <= len(rep.Gaps), "ouch")
+	assert.True(t, 50*time.Millisecond <= rep.GapDuration(), rep.String())
+	assert.Equal(t, int64(buf.Len()), rep.Bytes, "ouch")
+}
+
+func TestRipGiveUp(t *testing.T) {
+	var connects int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connects, 1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r := Ripper{Client: http.DefaultClient, Retries: 2, Backoff: time.Millisecond}
+	from := time.Now()
+	rep, err := r.Rip(context.Background(), mustParse(srv.URL+"/stream.mp3"), from, from.Add(5*time.Second), new(bytes.Buffer))
+	assert.True(t, strings.HasPrefix(err.Error(), "giving up after 2 retries: 503 Service Unavailable"), err.Error())
+	assert.Equal(t, int32(3), connects, "ouch")
+	assert.Equal(t, 0, rep.Connects, "ouch")
+	assert.Equal(t, int64(0), rep.Bytes, "ouch")
+}
+
+func TestRipCancel(t *testing.T) {
+	var connects int32
+	srv := streamServer(0, &connects)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	from := time.Now()
+	rep, err := Default.Rip(ctx, mustParse(srv.URL+"/stream.mp3"), from, from.Add(5*time.Second), new(bytes.Buffer))
+	assert.Equal(t, context.Canceled, err, "ouch")
+	assert.True(t, 0 < rep.Bytes, "ouch")
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"purl.mro.name/recorder/radio/podcast"
+	"purl.mro.name/recorder/radio/schedule"
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+func main() {
+	flag.Usage = commandHelp
+	stateFile := flag.String("state", "schedule.json", "file to persist the queue across restarts")
+	pre := flag.Duration("pre", 90*time.Second, "start recording that early")
+	post := flag.Duration("post", 20*time.Second, "stop recording that late")
+	keep := flag.Duration("keep", 7*24*time.Hour, "drop done and failed recordings from the queue that long after their end, 0: never")
+	ripCmd := flag.String("rip", "app/enclosure-rip.lua", "command to record, gets 'enclosures/<id>' as argument")
+	grace := flag.Duration("grace", 10*time.Second, "time the rip command gets to finish after SIGTERM before it's killed")
+	podcasts := flag.String("podcasts", "", "directory with <id>/app/podcast.json, queue only matching broadcasts")
+	addr := flag.String("http", "localhost:8089", "listen address for the queue http api")
+	flag.Parse()
+
+	var pcs []podcast.Podcast
+	if "" != *podcasts {
+		var err error
+		if pcs, err = podcast.LoadAll(*podcasts); nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			os.Exit(1)
+		}
+		if 0 == len(pcs) {
+			// rather than queue everything
+			fmt.Fprintf(os.Stderr, "error no */app/podcast.json in %s\n", *podcasts)
+			os.Exit(1)
+		}
+	}
+
+	rip := schedule.RipperFunc(func(ctx context.Context, job schedule.Job) error {
+		fmt.Fprintf(os.Stderr, "ripping %s\n", job.Identifier)
+		cmd := exec.Command(*ripCmd, "enclosures/"+job.Identifier)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		return runGracefully(ctx, cmd, *grace)
+	})
+	sched := schedule.New(schedule.SystemClock, rip, *stateFile, *pre, *post)
+	sched.Retention = *keep
+	if err := sched.Load(); nil != err {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+		os.Exit(1)
+	}
+
+	add := func(bc scrape.Broadcast) (job schedule.Job, ok bool) {
+		if "" != *podcasts && nil == podcast.Match(pcs, bc) {
+			return
+		}
+		job, err := sched.Add(bc)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "queued  %s\n", job.Identifier)
+		return job, true
+	}
+
+	for _, xmlFileName := range flag.Args() {
+		bc, err := pbmi.ReadFile(xmlFileName)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			continue
+		}
+		add(bc)
+	}
+
+	http.HandleFunc("/queue", func(w http.ResponseWriter, r *http.Request) {
+		var ret []schedule.Job
+		switch r.Method {
+		case "GET":
+			// ?state=pending|running|done|failed
+			for _, j := range sched.Queue() {
+				if st := r.URL.Query().Get("state"); "" == st || schedule.State(st) == j.State {
+					ret = append(ret, j)
+				}
+			}
+		case "POST":
+			// JSON Lines as written by 'scrape -format json'
+			bcs, err := scrape.ReadBroadcastsJSON(r.Body)
+			if nil != err {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, bc := range bcs {
+				if j, ok := add(bc); ok {
+					ret = append(ret, j)
+				}
+			}
+		case "DELETE":
+			if err := sched.Remove(r.URL.Query().Get("id")); nil != err {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ret)
+	})
+	go func() {
+		if err := http.ListenAndServe(*addr, nil); nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	sched.Run(ctx)
+}
+
+// Run cmd, on ctx done send SIGTERM and kill only if it's still running after grace.
+// exec.CommandContext would SIGKILL right away and rip-cmd couldn't close the
+// enclosure nor report.
+func runGracefully(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if err := cmd.Start(); nil != err {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if nil != cmd.Process.Signal(syscall.SIGTERM) {
+		cmd.Process.Kill() // e.g. windows
+	}
+	select {
+	case <-done:
+	case <-time.After(grace):
+		cmd.Process.Kill()
+		<-done
+	}
+	return ctx.Err()
+}
+
+func commandHelp() {
+	program := os.Args[0]
+	fmt.Printf("Usage: %s [options] [stations/b2/2016/08/25/1805\\ Bayern\\ 2-radioMusik.xml ...]\n", program)
+	fmt.Printf("\n")
+	fmt.Printf("records broadcasts at their time, a replacement for the at jobs of Enclosure.lua.\n")
+	fmt.Printf("\n")
+	fmt.Printf("  GET    /queue?state=pending    list the queue (pending, running, done, failed)\n")
+	fmt.Printf("  POST   /queue                  queue broadcasts, JSON Lines as from 'scrape -format json'\n")
+	fmt.Printf("  DELETE /queue?id=<id>          drop from the queue\n")
+	fmt.Printf("\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunGracefully(t *testing.T) {
+	// stand-in for rip-cmd, finishes its work on SIGTERM
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "trap 'echo closed; exit 0' TERM; while :; do sleep 0.01; done")
+	cmd.Stdout = &out
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	assert.Equal(t, context.Canceled, runGracefully(ctx, cmd, 5*time.Second), "ouch")
+	assert.Equal(t, "closed\n", out.String(), "got the chance to clean up")
+
+	// ignores SIGTERM, gets killed after grace
+	cmd = exec.Command("sh", "-c", "trap '' TERM; while :; do sleep 0.01; done")
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	t0 := time.Now()
+	assert.Equal(t, context.Canceled, runGracefully(ctx, cmd, 200*time.Millisecond), "ouch")
+	assert.True(t, 300*time.Millisecond <= time.Since(t0), "waited for grace")
+
+	cmd = exec.Command("sh", "-c", "exit 3")
+	assert.Equal(t, "exit status 3", runGracefully(context.Background(), cmd, time.Second).Error(), "ouch")
+}
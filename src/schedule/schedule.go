@@ -0,0 +1,350 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Recording scheduler, the successor of the at(1) jobs from
+// htdocs/app/Enclosure.lua schedule/unschedule.
+//
+// Keeps a timeline of recordings, starts a Ripper at DC.format.timestart
+// minus pre-padding and cancels it at DC.format.timeend plus post-padding.
+// The queue is persisted to a json file after each change.
+//
+// import "purl.mro.name/recorder/radio/schedule"
+
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+// Injectable time source.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var SystemClock Clock = systemClock{}
+
+// Records a job. Must return when ctx is done.
+type Ripper interface {
+	Rip(ctx context.Context, job Job) error
+}
+
+type RipperFunc func(ctx context.Context, job Job) error
+
+func (f RipperFunc) Rip(ctx context.Context, job Job) error {
+	return f(ctx, job)
+}
+
+type State string
+
+const (
+	Pending State = "pending"
+	Running State = "running"
+	Done    State = "done"
+	Failed  State = "failed"
+)
+
+type Job struct {
+	Identifier string    `json:"id"` // see pbmi.Identifier
+	Station    string    `json:"station"`
+	Title      string    `json:"title"`
+	Start      time.Time `json:"start"` // DC.format.timestart
+	End        time.Time `json:"end"`   // DC.format.timeend
+	State      State     `json:"state"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Scheduler struct {
+	Clock       Clock
+	Ripper      Ripper
+	PrePadding  time.Duration
+	PostPadding time.Duration
+	StateFile   string        // empty: don't persist
+	Retention   time.Duration // drop done and failed jobs that long after their end, 0: keep
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	wake    chan struct{}
+	wg      sync.WaitGroup
+	halting bool
+}
+
+func New(clock Clock, ripper Ripper, stateFile string, pre, post time.Duration) *Scheduler {
+	return &Scheduler{
+		Clock:       clock,
+		Ripper:      ripper,
+		PrePadding:  pre,
+		PostPadding: post,
+		StateFile:   stateFile,
+		jobs:        make(map[string]*Job),
+		cancels:     make(map[string]context.CancelFunc),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func (s *Scheduler) startAt(j *Job) time.Time { return j.Start.Add(-s.PrePadding) }
+func (s *Scheduler) stopAt(j *Job) time.Time  { return j.End.Add(s.PostPadding) }
+
+// Queue a broadcast for recording. Adding a pending broadcast again updates it.
+func (s *Scheduler) Add(bc scrape.Broadcast) (ret Job, err error) {
+	if nil == bc.DtEnd {
+		return ret, errors.New("missing DtEnd for " + bc.Source.String())
+	}
+	j := Job{
+		Identifier: pbmi.Identifier(bc),
+		Station:    bc.Station.Identifier,
+		Title:      bc.Title,
+		Start:      bc.Time,
+		End:        *bc.DtEnd,
+		State:      Pending,
+	}
+	if !j.Start.Before(j.End) {
+		return j, errors.New("dt <= 0 for " + j.Identifier)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.Clock.Now().Before(s.stopAt(&j)) {
+		return j, errors.New("is past: " + j.Identifier)
+	}
+	if old, ok := s.jobs[j.Identifier]; ok && Pending != old.State {
+		return *old, nil
+	}
+	s.jobs[j.Identifier] = &j
+	s.notify()
+	return j, s.save()
+}
+
+// Drop a job. A running recording gets cancelled.
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	delete(s.jobs, id)
+	return s.save()
+}
+
+// Snapshot of all jobs, sorted by start.
+func (s *Scheduler) Queue() (ret []Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret = make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		ret = append(ret, *j)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		if ret[a].Start.Equal(ret[b].Start) {
+			return ret[a].Identifier < ret[b].Identifier
+		}
+		return ret[a].Start.Before(ret[b].Start)
+	})
+	return
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start due and stop overdue recordings. Returns the time of the next
+// event, zero if there is none.
+func (s *Scheduler) Poll() (next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Clock.Now()
+	earliest := func(t time.Time) {
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	for id, j := range s.jobs {
+		switch j.State {
+		case Pending:
+			if !now.Before(s.stopAt(j)) {
+				j.State, j.Error = Failed, "missed"
+				s.persist()
+				if 0 < s.Retention {
+					earliest(s.stopAt(j).Add(s.Retention))
+				}
+				continue
+			}
+			if now.Before(s.startAt(j)) {
+				earliest(s.startAt(j))
+				continue
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			s.cancels[id] = cancel
+			j.State = Running
+			s.persist()
+			s.wg.Add(1)
+			go s.rip(ctx, *j)
+			earliest(s.stopAt(j))
+		case Running:
+			if now.Before(s.stopAt(j)) {
+				earliest(s.stopAt(j))
+			} else if cancel, ok := s.cancels[id]; ok {
+				cancel()
+			}
+		case Done, Failed:
+			if 0 >= s.Retention {
+				continue
+			}
+			if expire := s.stopAt(j).Add(s.Retention); now.Before(expire) {
+				earliest(expire)
+			} else {
+				delete(s.jobs, id)
+				s.persist()
+			}
+		}
+	}
+	return
+}
+
+func (s *Scheduler) rip(ctx context.Context, j Job) {
+	defer s.wg.Done()
+	err := s.Ripper.Rip(ctx, j)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.cancels[j.Identifier]; ok {
+		cancel()
+		delete(s.cancels, j.Identifier)
+	}
+	jj, ok := s.jobs[j.Identifier]
+	if !ok || s.halting {
+		// removed or shutdown, leave running to resume after restart (see Load)
+		return
+	}
+	if nil != err && context.Canceled != err {
+		jj.State, jj.Error = Failed, err.Error()
+	} else {
+		jj.State = Done
+	}
+	s.persist()
+	s.notify()
+}
+
+// Block and run the timeline until ctx is done. Running recordings get
+// cancelled and waited for on return.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.wg.Wait()
+	for {
+		var timer <-chan time.Time
+		if next := s.Poll(); !next.IsZero() {
+			timer = s.Clock.After(next.Sub(s.Clock.Now()))
+		}
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.halting = true
+			for _, cancel := range s.cancels {
+				cancel()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		case <-s.wake:
+		case <-timer:
+		}
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Persistence
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// must hold s.mu
+func (s *Scheduler) save() error {
+	if "" == s.StateFile {
+		return nil
+	}
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Identifier < jobs[b].Identifier })
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if nil != err {
+		return err
+	}
+	// write + rename so a crash never leaves a truncated file
+	tmp := s.StateFile + "~"
+	if err = ioutil.WriteFile(tmp, data, 0644); nil != err {
+		return err
+	}
+	return os.Rename(tmp, s.StateFile)
+}
+
+// save where no caller can take the error, the timeline goes on regardless.
+// must hold s.mu
+func (s *Scheduler) persist() {
+	if err := s.save(); nil != err {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+	}
+}
+
+// Reload the queue written before a restart. Recordings interrupted by the
+// restart are resumed if still within their window, failed otherwise.
+func (s *Scheduler) Load() error {
+	data, err := ioutil.ReadFile(s.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if nil != err {
+		return err
+	}
+	var jobs []*Job
+	if err = json.Unmarshal(data, &jobs); nil != err {
+		return errors.New(s.StateFile + ": " + err.Error())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Clock.Now()
+	for _, j := range jobs {
+		if Running == j.State {
+			if now.Before(s.stopAt(j)) {
+				j.State = Pending
+			} else {
+				j.State, j.Error = Failed, "interrupted"
+			}
+		}
+		s.jobs[j.Identifier] = j
+	}
+	s.notify()
+	return nil
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/schedule"
+//
+package schedule
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// A clock that only moves when told so
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	}
+	return ch
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	keep := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			keep = append(keep, w)
+		} else {
+			w.ch <- t
+		}
+	}
+	c.waiters = keep
+}
+
+// block until somebody waits for time to pass
+func (c *fakeClock) awaitWaiter() {
+	for {
+		c.mu.Lock()
+		n := len(c.waiters)
+		c.mu.Unlock()
+		if 0 < n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+func at(h, m, s int) time.Time {
+	return time.Date(2016, time.August, 25, h, m, s, 0, berlin)
+}
+
+func broadcast(title string, start, end time.Time) scrape.Broadcast {
+	bc := scrape.Broadcast{}
+	bc.Station = scrape.Station{Identifier: "b2", TimeZone: berlin}
+	bc.Title = title
+	bc.Time = start
+	bc.DtEnd = &end
+	return bc
+}
+
+// A ripper that reports start and blocks until cancelled.
+type blockingRipper struct {
+	started chan string
+}
+
+func (r blockingRipper) Rip(ctx context.Context, job Job) error {
+	r.started <- job.Identifier
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func state(s *Scheduler, id string) (State, string) {
+	for _, j := range s.Queue() {
+		if id == j.Identifier {
+			return j.State, j.Error
+		}
+	}
+	return "", ""
+}
+
+func TestPollStartStop(t *testing.T) {
+	clk := &fakeClock{now: at(18, 0, 0)}
+	rip := blockingRipper{started: make(chan string, 1)}
+	s := New(clk, rip, "", 90*time.Second, 15*time.Second)
+
+	j, err := s.Add(broadcast("Bayern 2-radioMusik", at(18, 5, 0), at(18, 30, 0)))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "b2/2016/08/25/1805 Bayern 2-radioMusik", j.Identifier, "ouch")
+	assert.Equal(t, Pending, j.State, "ouch")
+
+	assert.Equal(t, at(18, 3, 30), s.Poll(), "start incl. pre-padding")
+	st, _ := state(s, j.Identifier)
+	assert.Equal(t, Pending, st, "ouch")
+
+	clk.Set(at(18, 3, 30))
+	assert.Equal(t, at(18, 30, 15), s.Poll(), "stop incl. post-padding")
+	assert.Equal(t, j.Identifier, <-rip.started, "ouch")
+	st, _ = state(s, j.Identifier)
+	assert.Equal(t, Running, st, "ouch")
+
+	clk.Set(at(18, 30, 14))
+	assert.Equal(t, at(18, 30, 15), s.Poll(), "still running")
+
+	clk.Set(at(18, 30, 15))
+	assert.True(t, s.Poll().IsZero(), "nothing left to do")
+	s.wg.Wait()
+	st, _ = state(s, j.Identifier)
+	assert.Equal(t, Done, st, "ouch")
+}
+
+func TestRipFailed(t *testing.T) {
+	clk := &fakeClock{now: at(18, 10, 0)}
+	s := New(clk, RipperFunc(func(ctx context.Context, job Job) error {
+		return errors.New("stream gone")
+	}), "", 0, 0)
+
+	j, err := s.Add(broadcast("Bayern 2-radioMusik", at(18, 5, 0), at(18, 30, 0)))
+	assert.Nil(t, err, "late but not too late")
+	s.Poll()
+	s.wg.Wait()
+	st, msg := state(s, j.Identifier)
+	assert.Equal(t, Failed, st, "ouch")
+	assert.Equal(t, "stream gone", msg, "ouch")
+}
+
+func TestMissed(t *testing.T) {
+	clk := &fakeClock{now: at(18, 0, 0)}
+	s := New(clk, blockingRipper{started: make(chan string, 1)}, "", 0, 0)
+
+	_, err := s.Add(broadcast("Vorbei", at(17, 0, 0), at(18, 0, 0)))
+	assert.Equal(t, "is past: b2/2016/08/25/1700 Vorbei", err.Error(), "ouch")
+
+	bc := broadcast("Ohne Ende", at(18, 5, 0), at(18, 30, 0))
+	bc.DtEnd = nil
+	_, err = s.Add(bc)
+	assert.NotNil(t, err, "ouch")
+
+	j, err := s.Add(broadcast("Bayern 2-radioMusik", at(18, 5, 0), at(18, 30, 0)))
+	assert.Nil(t, err, "ouch")
+	clk.Set(at(19, 0, 0)) // e.g. the machine was suspended
+	s.Poll()
+	st, msg := state(s, j.Identifier)
+	assert.Equal(t, Failed, st, "ouch")
+	assert.Equal(t, "missed", msg, "ouch")
+}
+
+func TestRetention(t *testing.T) {
+	clk := &fakeClock{now: at(18, 0, 0)}
+	s := New(clk, blockingRipper{started: make(chan string, 1)}, "", 0, 0)
+	s.Retention = time.Hour
+	j, err := s.Add(broadcast("Bayern 2-radioMusik", at(18, 5, 0), at(18, 30, 0)))
+	assert.Nil(t, err, "ouch")
+
+	clk.Set(at(19, 0, 0))
+	assert.Equal(t, at(19, 30, 0), s.Poll(), "wake up to expire")
+	st, _ := state(s, j.Identifier)
+	assert.Equal(t, Failed, st, "ouch")
+	clk.Set(at(19, 29, 0))
+	s.Poll()
+	assert.Equal(t, 1, len(s.Queue()), "still kept")
+	clk.Set(at(19, 30, 0))
+	assert.True(t, s.Poll().IsZero(), "ouch")
+	assert.Equal(t, 0, len(s.Queue()), "expired")
+}
+
+func TestQueueSortedAndRemove(t *testing.T) {
+	clk := &fakeClock{now: at(18, 0, 0)}
+	s := New(clk, blockingRipper{started: make(chan string, 1)}, "", 0, 0)
+	s.Add(broadcast("Zwei", at(19, 5, 0), at(20, 0, 0)))
+	s.Add(broadcast("Eins", at(18, 5, 0), at(19, 5, 0)))
+	q := s.Queue()
+	assert.Equal(t, 2, len(q), "ouch")
+	assert.Equal(t, "Eins", q[0].Title, "ouch")
+	assert.Equal(t, "Zwei", q[1].Title, "ouch")
+
+	assert.Nil(t, s.Remove(q[0].Identifier), "ouch")
+	q = s.Queue()
+	assert.Equal(t, 1, len(q), "ouch")
+	assert.Equal(t, "Zwei", q[0].Title, "ouch")
+
+	s.StateFile = "testdata/no/such/dir/schedule.json"
+	assert.NotNil(t, s.Remove(q[0].Identifier), "can't persist")
+	assert.Equal(t, 0, len(s.Queue()), "removed nevertheless")
+}
+
+func TestRunWithFakeClock(t *testing.T) {
+	clk := &fakeClock{now: at(18, 0, 0)}
+	rip := blockingRipper{started: make(chan string, 1)}
+	s := New(clk, rip, "", 90*time.Second, 15*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ret := make(chan error)
+	go func() { ret <- s.Run(ctx) }()
+
+	j, err := s.Add(broadcast("Bayern 2-radioMusik", at(18, 5, 0), at(18, 30, 0)))
+	assert.Nil(t, err, "ouch")
+
+	clk.awaitWaiter()
+	clk.Set(at(18, 3, 30))
+	assert.Equal(t, j.Identifier, <-rip.started, "ouch")
+
+	clk.awaitWaiter()
+	clk.Set(at(18, 31, 0))
+	for Done != func() State { st, _ := state(s, j.Identifier); return st }() {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-ret, "ouch")
+}
+
+func TestRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "schedule.json")
+
+	clk := &fakeClock{now: at(18, 0, 0)}
+	rip := blockingRipper{started: make(chan string, 2)}
+	s := New(clk, rip, file, 0, 0)
+	s.Add(broadcast("Eins", at(18, 0, 0), at(18, 30, 0)))
+	s.Add(broadcast("Zwei", at(18, 30, 0), at(19, 0, 0)))
+
+	// shutdown while recording 'Eins'
+	ctx, cancel := context.WithCancel(context.Background())
+	ret := make(chan error)
+	go func() { ret <- s.Run(ctx) }()
+	<-rip.started
+	cancel()
+	assert.Equal(t, context.Canceled, <-ret, "ouch")
+
+	{
+		s1 := New(clk, rip, file, 0, 0)
+		err = s1.Load()
+		assert.Nil(t, err, "ouch")
+		q := s1.Queue()
+		assert.Equal(t, 2, len(q), "ouch")
+		assert.Equal(t, Pending, q[0].State, "resume interrupted recording")
+		assert.Equal(t, Pending, q[1].State, "ouch")
+	}
+	{
+		clk.Set(at(18, 40, 0))
+		s1 := New(clk, rip, file, 0, 0)
+		err = s1.Load()
+		assert.Nil(t, err, "ouch")
+		q := s1.Queue()
+		assert.Equal(t, Failed, q[0].State, "too late to resume")
+		assert.Equal(t, "interrupted", q[0].Error, "ouch")
+		assert.Equal(t, Pending, q[1].State, "ouch")
+	}
+
+	{
+		s1 := New(clk, rip, filepath.Join(dir, "nonexistent.json"), 0, 0)
+		assert.Nil(t, s1.Load(), "no state is fine")
+		ioutil.WriteFile(file, []byte("kaputt"), 0644)
+		s1 = New(clk, rip, file, 0, 0)
+		assert.NotNil(t, s1.Load(), "ouch")
+	}
+}
@@ -15,11 +15,12 @@ rm "${PROG_NAME}"-*-"${VERSION}" 2>/dev/null
 
 # cd ../scrape
 go get -u github.com/yhat/scrape
+go get -u golang.org/x/net/html/charset
 go get -u github.com/stretchr/testify
 
 CWD="$(pwd)"
 cd ..
-for dir in scrape-cmd scrape scrape/br scrape/b3 scrape/b4 scrape/dlf scrape/m945 scrape/radiofabrik scrape/wdr
+for dir in scrape-cmd scrape scrape/br scrape/b3 scrape/b4 scrape/dlf scrape/m945 scrape/pbmi scrape/radiofabrik scrape/store scrape/wdr
 do
   cd "${CWD}/../${dir}"
   go fmt && go test ; \
@@ -0,0 +1,117 @@
+// Copyright (c) 2015-2016 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+// A station's day containing now and what's wrong with it.
+type dayCheck struct {
+	station    string
+	from       time.Time
+	until      time.Time
+	broadcasts []scrape.Broadcast
+	problems   []string
+}
+
+func (c dayCheck) write(w io.Writer) {
+	if 0 == len(c.problems) {
+		fmt.Fprintf(w, "check %-11s ok     %4d broadcasts %s - %s\n", c.station, len(c.broadcasts), c.from.Format("2006-01-02 15:04"), c.until.Format("2006-01-02 15:04"))
+		return
+	}
+	fmt.Fprintf(w, "check %-11s FAILED %4d problems\n", c.station, len(c.problems))
+	for _, p := range c.problems {
+		fmt.Fprintf(w, "      %s\n", p)
+	}
+}
+
+// Scrape the day containing now of each seed station and CheckDay it.
+// Reports to w, returns false if there are problems.
+func check(w io.Writer, seeds []job, now time.Time, timeout time.Duration, workers int) bool {
+	stations := make(map[string]scrape.Station)
+	for _, st := range scrape.Stations() {
+		stations[st.Identifier] = st
+	}
+	checks := make([]*dayCheck, 0, len(seeds))
+	byStation := make(map[string]*dayCheck)
+	var first, last time.Time
+	for _, jo := range seeds {
+		st := stations[jo.stats.Station]
+		c := &dayCheck{station: st.Identifier, from: st.DayStart(now)}
+		c.until = st.DayStart(c.from.Add(36 * time.Hour))
+		checks = append(checks, c)
+		byStation[c.station] = c
+		if first.IsZero() || c.from.Before(first) {
+			first = c.from
+		}
+		if c.until.After(last) {
+			last = c.until
+		}
+	}
+	// hourly, so all the detail pages of the day are due, see Scraper.Matches
+	var nows []time.Time
+	for t := first.Add(-time.Hour); t.Before(last); t = t.Add(time.Hour) {
+		nows = append(nows, t)
+	}
+
+	var mu sync.Mutex
+	collect := func(b scrape.Broadcaster, _ io.Writer) error {
+		bc, ok := scrape.AsBroadcast(b)
+		if !ok {
+			return fmt.Errorf("not a broadcast: %v", b)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if c, ok := byStation[bc.Station.Identifier]; ok {
+			c.broadcasts = append(c.broadcasts, bc)
+		}
+		return nil
+	}
+	var queued int64
+	rep := run(context.Background(), seeds, nows, timeout, workers, collect, &queued)
+
+	mu.Lock()
+	defer mu.Unlock()
+	stats := make(map[string]*scrape.Stats)
+	for _, st := range rep.Stations {
+		stats[st.Station] = st
+	}
+	ok := true
+	for _, c := range checks {
+		if st := stats[c.station]; nil != st && (0 < st.HttpErrors || 0 < st.ParseErrors) {
+			c.problems = append(c.problems, fmt.Sprintf("%d http errors, %d parse errors", st.HttpErrors, st.ParseErrors))
+		}
+		c.problems = append(c.problems, scrape.CheckDay(c.broadcasts, c.from, c.until)...)
+		c.write(w)
+		ok = ok && 0 == len(c.problems)
+	}
+	if "" != rep.Error {
+		fmt.Fprintf(w, "check incomplete: %s\n", rep.Error)
+		ok = false
+	}
+	return ok
+}
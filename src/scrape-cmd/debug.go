@@ -0,0 +1,95 @@
+// Copyright (c) 2015-2016 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+// Time, Source and Title of a job, as far as it has them.
+func describe(s scrape.Scraper) string {
+	ret := fmt.Sprintf("%T", s)
+	v := reflect.Indirect(reflect.ValueOf(s))
+	if reflect.Struct != v.Kind() {
+		return ret
+	}
+	if f := v.FieldByName("Time"); f.IsValid() {
+		if t, ok := f.Interface().(time.Time); ok {
+			ret += " " + t.Format(time.RFC3339)
+		}
+	}
+	if f := v.FieldByName("Source"); f.IsValid() {
+		if u, ok := f.Interface().(url.URL); ok {
+			ret += " " + u.String()
+		}
+	}
+	if f := v.FieldByName("Title"); f.IsValid() && "" != f.String() {
+		ret += " '" + f.String() + "'"
+	}
+	return ret
+}
+
+// Run a single level of a station on a url or a saved page. Prints the
+// follow-up jobs and the broadcasts found but doesn't recurse. A saved page
+// pretends to come from the station's program url.
+func debug(w io.Writer, id string, level string, src string, day time.Time) error {
+	ctx := context.Background()
+	var source url.URL
+	if f, err := os.Open(src); nil == err {
+		defer f.Close()
+		ctx = scrape.WithBody(ctx, f)
+		for _, st := range scrape.Stations() {
+			if id == st.Identifier && nil != st.ProgramURL {
+				source = *st.ProgramURL
+			}
+		}
+	} else {
+		u, err := url.Parse(src)
+		if nil != err || !u.IsAbs() {
+			return fmt.Errorf("neither a file nor an absolute url: %s", src)
+		}
+		source = *u
+	}
+	s, err := scrape.NewLevelScraper(id, level, source, day)
+	if nil != err {
+		return err
+	}
+	jobs, results, err := s.Scrape(ctx)
+	for _, j := range jobs {
+		fmt.Fprintf(w, "job %s\n", describe(j))
+	}
+	for _, b := range results {
+		data, e := json.MarshalIndent(b, "", "  ")
+		if nil != e {
+			return e
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015-2016 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type stationMetrics struct {
+	duration        float64 // last run
+	broadcasts      int     // last run
+	broadcastsTotal int
+	httpErrors      int // last run
+	parseErrors     int // last run
+	lastSuccess     int64
+}
+
+type hostStatus struct {
+	host   string
+	status int
+}
+
+// Prometheus text exposition of the daemon's runs.
+type metrics struct {
+	mu          sync.Mutex
+	runs        int
+	runDuration float64 // last run
+	runErrors   int
+	stations    map[string]*stationMetrics
+	responses   map[hostStatus]int
+	queued      *int64
+}
+
+func newMetrics(queued *int64) *metrics {
+	return &metrics{
+		stations:  make(map[string]*stationMetrics),
+		responses: make(map[hostStatus]int),
+		queued:    queued,
+	}
+}
+
+// see scrape.OnResponse
+func (m *metrics) response(host string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[hostStatus{host, status}]++
+}
+
+func (m *metrics) run(rep report) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs++
+	m.runDuration = rep.End.Sub(rep.Start).Seconds()
+	if "" != rep.Error {
+		m.runErrors++
+	}
+	for _, st := range rep.Stations {
+		sm, ok := m.stations[st.Station]
+		if !ok {
+			sm = &stationMetrics{}
+			m.stations[st.Station] = sm
+		}
+		sm.duration = st.WallTime
+		sm.broadcasts = st.Broadcasts
+		sm.broadcastsTotal += st.Broadcasts
+		sm.httpErrors = st.HttpErrors
+		sm.parseErrors = st.ParseErrors
+		// a changed site layout typically yields no broadcasts, but no errors either
+		if 0 == st.HttpErrors+st.ParseErrors && 0 < st.Broadcasts {
+			sm.lastSuccess = rep.End.Unix()
+		}
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	family := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	family("scrape_runs_total", "counter", "Finished scrape runs.")
+	fmt.Fprintf(w, "scrape_runs_total %d\n", m.runs)
+	family("scrape_run_errors_total", "counter", "Scrape runs hitting the deadline.")
+	fmt.Fprintf(w, "scrape_run_errors_total %d\n", m.runErrors)
+	family("scrape_run_duration_seconds", "gauge", "Wall time of the last run.")
+	fmt.Fprintf(w, "scrape_run_duration_seconds %g\n", m.runDuration)
+	family("scrape_jobs_queued", "gauge", "Scrape jobs waiting for a worker.")
+	fmt.Fprintf(w, "scrape_jobs_queued %d\n", atomic.LoadInt64(m.queued))
+
+	ids := make([]string, 0, len(m.stations))
+	for id := range m.stations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	perStation := func(name, typ, help string, val func(*stationMetrics) string) {
+		family(name, typ, help)
+		for _, id := range ids {
+			fmt.Fprintf(w, "%s{station=\"%s\"} %s\n", name, escapeLabel(id), val(m.stations[id]))
+		}
+	}
+	perStation("scrape_station_duration_seconds", "gauge", "Wall time of the station in the last run.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%g", sm.duration) })
+	perStation("scrape_station_broadcasts", "gauge", "Broadcasts scraped in the last run.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%d", sm.broadcasts) })
+	perStation("scrape_station_broadcasts_total", "counter", "Broadcasts scraped.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%d", sm.broadcastsTotal) })
+	perStation("scrape_station_http_errors", "gauge", "Failed fetches in the last run.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%d", sm.httpErrors) })
+	perStation("scrape_station_parse_errors", "gauge", "Failed parses in the last run.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%d", sm.parseErrors) })
+	perStation("scrape_station_last_success_timestamp_seconds", "gauge", "End of the last run without errors and with broadcasts.",
+		func(sm *stationMetrics) string { return fmt.Sprintf("%d", sm.lastSuccess) })
+
+	keys := make([]hostStatus, 0, len(m.responses))
+	for k := range m.responses {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].host == keys[b].host {
+			return keys[a].status < keys[b].status
+		}
+		return keys[a].host < keys[b].host
+	})
+	family("scrape_http_responses_total", "counter", "Http responses per host and status, 0 for transport errors.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "scrape_http_responses_total{host=\"%s\",code=\"%d\"} %d\n", escapeLabel(k.host), k.status, m.responses[k])
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+func TestMetrics(t *testing.T) {
+	queued := int64(3)
+	m := newMetrics(&queued)
+	m.response("www.br.de", 200)
+	m.response("www.br.de", 200)
+	m.response("www.br.de", 503)
+	m.response("www.wdr.de", 0)
+
+	t0 := time.Unix(1472140800, 0)
+	b2 := scrape.NewStats("b2")
+	b2.Job(t0, 0, 12, nil)
+	wdr5 := scrape.NewStats("wdr5")
+	wdr5.Job(t0, 0, 0, nil)
+	m.run(report{Start: t0, End: t0.Add(90 * time.Second), Stations: []*scrape.Stats{b2.Snapshot(), wdr5.Snapshot()}})
+
+	var buf bytes.Buffer
+	m.write(&buf)
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE scrape_runs_total counter",
+		"scrape_runs_total 1",
+		"scrape_run_duration_seconds 90",
+		"scrape_jobs_queued 3",
+		`scrape_station_broadcasts{station="b2"} 12`,
+		`scrape_station_broadcasts{station="wdr5"} 0`,
+		`scrape_station_broadcasts_total{station="b2"} 12`,
+		`scrape_station_last_success_timestamp_seconds{station="b2"} 1472140890`,
+		`scrape_station_last_success_timestamp_seconds{station="wdr5"} 0`,
+		`scrape_http_responses_total{host="www.br.de",code="200"} 2`,
+		`scrape_http_responses_total{host="www.br.de",code="503"} 1`,
+		`scrape_http_responses_total{host="www.wdr.de",code="0"} 1`,
+	} {
+		assert.True(t, strings.Contains(out, line+"\n"), line)
+	}
+}
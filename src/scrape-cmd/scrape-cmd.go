@@ -20,93 +20,477 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"purl.mro.name/recorder/radio/scrape"
-	"purl.mro.name/recorder/radio/scrape/b3"
-	"purl.mro.name/recorder/radio/scrape/b4"
-	"purl.mro.name/recorder/radio/scrape/br"
-	"purl.mro.name/recorder/radio/scrape/dlf"
-	"purl.mro.name/recorder/radio/scrape/m945"
-	"purl.mro.name/recorder/radio/scrape/radiofabrik"
-	"purl.mro.name/recorder/radio/scrape/wdr"
+	_ "purl.mro.name/recorder/radio/scrape/b3"
+	_ "purl.mro.name/recorder/radio/scrape/b4"
+	_ "purl.mro.name/recorder/radio/scrape/br"
+	_ "purl.mro.name/recorder/radio/scrape/dlf"
+	_ "purl.mro.name/recorder/radio/scrape/m945"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+	_ "purl.mro.name/recorder/radio/scrape/radiofabrik"
+	_ "purl.mro.name/recorder/radio/scrape/spi"
+	"purl.mro.name/recorder/radio/scrape/store"
+	_ "purl.mro.name/recorder/radio/scrape/wdr"
+	"purl.mro.name/recorder/radio/scrape/xmltv"
 )
 
-func main() {
-	jobs := make(chan scrape.Scraper, 15)    // concurrent
+// A scrape job and the stats of the station it descends from.
+type job struct {
+	scrape.Scraper
+	stats *scrape.Stats
+}
+
+// Written to -report at the end of a run.
+type report struct {
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	Error      string          `json:"error,omitempty"`
+	Stations   []*scrape.Stats `json:"stations"`
+	Duplicates int             `json:"duplicates"` // broadcasts scraped more than once, see scrape.Merger
+	Conflicts  []string        `json:"conflicts,omitempty"`
+	Seams      []string        `json:"seams,omitempty"` // gaps and overlaps, see scrape.Stitch
+}
+
+func newReport(start time.Time, err error, stations []*scrape.Stats) (ret report) {
+	ret.Start, ret.End = start, time.Now()
+	if nil != err {
+		ret.Error = err.Error()
+	}
+	for _, st := range stations {
+		ret.Stations = append(ret.Stations, st.Snapshot())
+	}
+	sort.Slice(ret.Stations, func(a, b int) bool { return ret.Stations[a].Station < ret.Stations[b].Station })
+	return
+}
+
+func (rep report) summary(w io.Writer) {
+	for _, st := range rep.Stations {
+		fmt.Fprintf(w, "summary %-11s %4d jobs %3d retries %3d http errors %3d parse errors %4d broadcasts %5.1fs\n", st.Station, st.Jobs, st.Retries, st.HttpErrors, st.ParseErrors, st.Broadcasts, st.WallTime)
+	}
+}
+
+func (rep report) write(file string) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if nil != err {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// The root jobs of the given stations, "all" for all registered ones.
+func seeds(ids []string) (ret []job, err error) {
+	if 1 == len(ids) && "all" == ids[0] {
+		ids = nil
+		for _, st := range scrape.Stations() {
+			ids = append(ids, st.Identifier)
+		}
+	}
+	for _, id := range ids {
+		s, err := scrape.NewScraper(id)
+		if nil != err {
+			return nil, err
+		}
+		ret = append(ret, job{s, scrape.NewStats(id)})
+	}
+	return
+}
+
+func list(w io.Writer) {
+	for _, st := range scrape.Stations() {
+		tz := ""
+		if nil != st.TimeZone {
+			tz = st.TimeZone.String()
+		}
+		fmt.Fprintf(w, "%-11s %-5s %-13s %s\n", st.Identifier, st.CloseDown, tz, st.ProgramURL)
+	}
+}
+
+// A panicking scraper fails its job, not the whole run.
+func scrapeJob(ctx context.Context, s scrape.Scraper) (jobs []scrape.Scraper, bcs []scrape.Broadcaster, retries int, err error) {
+	defer func() {
+		if p := recover(); nil != p {
+			err = fmt.Errorf("panic %v", p)
+		}
+	}()
+	return scrape.DefaultBackoff.Scrape(ctx, s)
+}
+
+// Same for writing a broadcast.
+func writeJob(write func(scrape.Broadcaster, io.Writer) error, bc scrape.Broadcaster) (err error) {
+	defer func() {
+		if p := recover(); nil != p {
+			err = fmt.Errorf("panic %v", p)
+		}
+	}()
+	return write(bc, os.Stdout)
+}
+
+// One scrape of the seeds and their jobs matching nows, bounded by timeout.
+// queued counts the jobs waiting for a worker.
+func run(parent context.Context, seeds []job, nows []time.Time, timeout time.Duration, workers int, write func(scrape.Broadcaster, io.Writer) error, queued *int64) report {
+	jobs := make(chan job, 15)               // concurrent
 	results := make(chan scrape.Broadcaster) // sequential
-	defer close(jobs)
-	defer close(results)
+	quit := make(chan struct{})              // stop workers and writer
+	defer close(quit)
 
 	var wgJobs sync.WaitGroup
 	var wgResults sync.WaitGroup
 
-	nows := scrape.IncrementalNows(time.Now())
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	// callers wgJobs.Add(1) beforehand, so the run can't end while a job is on its way
+	enqueue := func(jo job) {
+		atomic.AddInt64(queued, 1)
+		select {
+		case jobs <- jo:
+		case <-quit:
+			atomic.AddInt64(queued, -1)
+		}
+	}
 
 	// scrape and write concurrently
 
-	// scraper loop
-	go func() {
-		for jobb := range jobs {
-			job := jobb
-			go func() {
-				defer wgJobs.Done()
-				// fmt.Fprintf(os.Stderr, "jobs process %p %s\n", job, job)
-				scrapers, bcs, err := job.Scrape()
+	// worker pool
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				var jo job
+				select {
+				case jo = <-jobs:
+				case <-quit:
+					return
+				}
+				atomic.AddInt64(queued, -1)
+				// fmt.Fprintf(os.Stderr, "jobs process %p %s\n", jo.Scraper, jo.Scraper)
+				t0 := time.Now()
+				scrapers, bcs, retries, err := scrapeJob(scrape.WithStats(ctx, jo.stats), jo.Scraper)
 				if nil != err {
-					fmt.Fprintf(os.Stderr, "error %s %s\n", job, err)
+					fmt.Fprintf(os.Stderr, "error %s %s\n", jo.Scraper, err)
 				}
+				jo.stats.Job(t0, retries, len(bcs), err)
 				for _, s := range scrapers {
-					if s.Matches(nows) {
-						wgJobs.Add(1)
+					if s.Matches(nows) && nil == ctx.Err() {
 						// fmt.Fprintf(os.Stderr, "jobs queue   %p %s\n", s, s)
-						jobs <- s
+						// don't block the worker on a full queue
+						wgJobs.Add(1)
+						go enqueue(job{s, jo.stats})
 					}
 				}
 				for _, b := range bcs {
 					wgResults.Add(1)
-					results <- b
+					select {
+					case results <- b:
+					case <-quit:
+					}
 				}
-			}()
+				wgJobs.Done()
+			}
+		}()
+	}
+
+	// merge loop, overlapping scrapes yield the same broadcast more than once
+	merger := scrape.NewMerger()
+	go func() {
+		for {
+			select {
+			case bc := <-results:
+				if b, ok := scrape.AsBroadcast(bc); ok {
+					merger.Add(b)
+				} else if err := writeJob(write, bc); nil != err {
+					fmt.Fprintf(os.Stderr, "error %s\n", err)
+				}
+				wgResults.Done()
+			case <-quit:
+				return
+			}
 		}
 	}()
 
-	// write loop
+	var stations []*scrape.Stats
+	for _, jo := range seeds {
+		stations = append(stations, jo.stats)
+		wgJobs.Add(1)
+		enqueue(jo)
+	}
+
+	done := make(chan struct{})
 	go func() {
-		for bc := range results {
-			func() {
-				defer wgResults.Done()
-				bc.WriteAsLuaTable(os.Stdout)
-			}()
-		}
+		wgJobs.Wait()
+		wgResults.Wait()
+		close(done)
 	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// give the running jobs a moment to notice
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+		err = ctx.Err()
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+	}
 
-	{
-		// seed all the radio stations to scrape
-		for _, s := range []string{"b1", "b2", "b5", "b+", "brheimat", "puls"} {
-			wgJobs.Add(1)
-			jobs <- br.Station(s)
+	// write what made it, merged and stitched
+	bcs, seams := scrape.Stitch(merger.Broadcasts())
+	for _, bc := range bcs {
+		if e := writeJob(write, bc); nil != e {
+			fmt.Fprintf(os.Stderr, "error %s\n", e)
 		}
-		wgJobs.Add(1)
-		jobs <- b3.Station("b3")
-		wgJobs.Add(1)
-		jobs <- b4.Station("b4")
+	}
+	rep := newReport(start, err, stations)
+	rep.Duplicates = merger.Duplicates()
+	for _, c := range merger.Conflicts() {
+		fmt.Fprintf(os.Stderr, "conflict %s\n", c)
+		rep.Conflicts = append(rep.Conflicts, c.String())
+	}
+	for _, sm := range seams {
+		fmt.Fprintf(os.Stderr, "%s\n", sm)
+		rep.Seams = append(rep.Seams, sm.String())
+	}
+	return rep
+}
 
-		wgJobs.Add(1)
-		jobs <- radiofabrik.Station("radiofabrik")
-		wgJobs.Add(1)
-		jobs <- m945.Station("m945")
-		for _, s := range []string{"dlf", "drk"} {
-			wgJobs.Add(1)
-			jobs <- dlf.Station(s)
+func main() {
+	format := flag.String("format", "lua", "output format: 'lua' (tables for broadcast-render.lua --luatables), 'json' (JSON Lines), 'xml' (write stations/<id>.xml) or 'xmltv' (an XMLTV document per run)")
+	root := flag.String("root", ".", "directory containing stations/ for -format xml")
+	updatePast := flag.Bool("update-past", false, "with -format xml also overwrite already started or past broadcasts")
+	timeout := flag.Duration("timeout", 50*time.Minute, "deadline for the whole run, so a hung station can't block the next one")
+	requestTimeout := flag.Duration("request-timeout", scrape.RequestTimeout, "deadline for each http request")
+	workers := flag.Int("workers", 8, "number of scrape jobs running in parallel")
+	flag.IntVar(&scrape.DefaultHostLimit.Concurrency, "host-concurrency", scrape.DefaultHostLimit.Concurrency, "parallel requests per host unless the station knows better (0: unlimited)")
+	flag.DurationVar(&scrape.DefaultHostLimit.Interval, "host-interval", scrape.DefaultHostLimit.Interval, "min. time between requests per host unless the station knows better")
+	flag.StringVar(&scrape.UserAgent, "user-agent", scrape.UserAgent, "http User-Agent header")
+	flag.IntVar(&scrape.DefaultBackoff.Retries, "retries", scrape.DefaultBackoff.Retries, "retries for transient failures (timeouts, 5xx, connection reset)")
+	flag.DurationVar(&scrape.DefaultBackoff.Initial, "backoff", scrape.DefaultBackoff.Initial, "delay before the first retry, doubled for each further one")
+	reportFile := flag.String("report", "", "write a json run report with per station numbers to this file")
+	cache := flag.String("cache", "", "directory for an http cache with conditional requests (empty: none)")
+	cacheAge := flag.Duration("cache-age", 30*24*time.Hour, "drop cache entries unused for that long (0: never)")
+	cacheSize := flag.Int64("cache-size", 256, "MB to keep in the cache, least recently used go first (0: no limit)")
+	daemon := flag.Bool("daemon", false, "keep running and scrape every -every instead of once (replaces cron/hourly.sh)")
+	every := flag.Duration("every", time.Hour, "with -daemon time between the starts of two runs")
+	addr := flag.String("http", "localhost:9108", "with -daemon serve prometheus /metrics here (empty: don't)")
+	stations := flag.String("stations", "stations", "directory with <id>/app/station.cfg and <id>/about.rdf overriding the built-in station data, if present")
+	listOnly := flag.Bool("list", false, "print the known stations with close down time, time zone and program url and exit")
+	record := flag.String("record", "", "also write all http responses to this directory, e.g. fixtures for -replay or tests")
+	replay := flag.String("replay", "", "serve http responses from this directory written by -record instead of going online")
+	debugLevel := flag.String("debug", "", "run only this parser level ('station', 'day', 'broadcast', ...) of one station on a url or saved page, print the result and exit")
+	debugDay := flag.String("day", "", "with -debug the date (2006-01-02) the level above would pass along (default today)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [station ...|all]\n       %s [options] check [station ...|all]\n       %s -debug level [-day date] station url|file\n\nScrapes all stations without arguments. 'check' scrapes today's schedules and reports\ngaps, overlaps, missing titles and implausible durations, exits 1 if there are any.\n\n", os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := scrape.LoadStations(*stations); nil != err && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+		os.Exit(2)
+	}
+	if *listOnly {
+		list(os.Stdout)
+		return
+	}
+	scrape.RequestTimeout = *requestTimeout
+	prune := func() {}
+	if "" != *cache {
+		scrape.Cache = &scrape.HttpCache{Dir: *cache, MaxAge: *cacheAge, MaxBytes: *cacheSize << 20}
+		prune = func() {
+			if err := scrape.Cache.Prune(time.Now()); nil != err && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "cache %s\n", err)
+			}
 		}
-		wgJobs.Add(1)
-		jobs <- wdr.Station("wdr5")
+		prune()
+	}
+	switch {
+	case "" != *record && "" != *replay:
+		fmt.Fprintf(os.Stderr, "error either -record or -replay\n")
+		os.Exit(2)
+	case "" != *record:
+		scrape.Client.Transport = &scrape.Fixtures{Dir: *record, Record: true}
+	case "" != *replay:
+		fx := &scrape.Fixtures{Dir: *replay}
+		t, err := fx.Recorded()
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			os.Exit(2)
+		}
+		if !t.IsZero() {
+			// ask for what was asked back then
+			scrape.Now = func() time.Time { return t }
+		}
+		scrape.Client.Transport = fx
 	}
 
-	wgJobs.Wait()
-	wgResults.Wait()
+	if "" != *debugLevel {
+		if 2 != flag.NArg() {
+			flag.Usage()
+			os.Exit(2)
+		}
+		day := time.Now()
+		if "" != *debugDay {
+			var err error
+			if day, err = time.Parse("2006-01-02", *debugDay); nil != err {
+				fmt.Fprintf(os.Stderr, "error %s\n", err)
+				os.Exit(2)
+			}
+		}
+		if err := debug(os.Stdout, flag.Arg(0), *debugLevel, flag.Arg(1), day); nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	ids := flag.Args()
+	checkOnly := 0 < len(ids) && "check" == ids[0]
+	if checkOnly {
+		ids = ids[1:]
+	}
+	if 0 == len(ids) {
+		ids = []string{"all"}
+	}
+	// fail early on unknown stations
+	if _, err := seeds(ids); nil != err {
+		fmt.Fprintf(os.Stderr, "error %s\n", err)
+		os.Exit(2)
+	}
+
+	if checkOnly {
+		js, _ := seeds(ids)
+		if !check(os.Stdout, js, scrape.Now(), *timeout, *workers) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// a writer per run, and what to do after the run
+	var writer func() func(scrape.Broadcaster, io.Writer) error
+	flush := func() error { return nil }
+	switch *format {
+	case "lua":
+		writer = func() func(scrape.Broadcaster, io.Writer) error { return scrape.Broadcaster.WriteAsLuaTable }
+	case "json":
+		writer = func() func(scrape.Broadcaster, io.Writer) error { return scrape.Broadcaster.WriteAsJSON }
+	case "xml":
+		st := store.Store{Root: *root}
+		writer = func() func(scrape.Broadcaster, io.Writer) error {
+			timeLimitMin := time.Now()
+			return func(b scrape.Broadcaster, _ io.Writer) (err error) {
+				bc, ok := scrape.AsBroadcast(b)
+				if !ok {
+					return fmt.Errorf("not a broadcast: %v", b)
+				}
+				msg := "ignored"
+				// DO only overwrite already started or past broadcasts if explicitely told
+				if *updatePast || bc.Time.After(timeLimitMin) {
+					var changed bool
+					if _, changed, err = st.Save(bc); nil != err {
+						return
+					}
+					msg = "unchang"
+					if changed {
+						msg = "written"
+					}
+				}
+				fmt.Fprintf(os.Stderr, "%-7s %s\n", msg, pbmi.Identifier(bc))
+				return
+			}
+		}
+	case "xmltv":
+		var doc *xmltv.Document
+		writer = func() func(scrape.Broadcaster, io.Writer) error {
+			doc = xmltv.NewDocument()
+			return func(b scrape.Broadcaster, _ io.Writer) error {
+				bc, ok := scrape.AsBroadcast(b)
+				if !ok {
+					return fmt.Errorf("not a broadcast: %v", b)
+				}
+				doc.Add(bc)
+				return nil
+			}
+		}
+		flush = func() error { return doc.Write(os.Stdout) }
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format '%s'\n", *format)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	finish := func(rep report) {
+		if err := flush(); nil != err {
+			fmt.Fprintf(os.Stderr, "error %s\n", err)
+		}
+		rep.summary(os.Stderr)
+		if "" != *reportFile {
+			if err := rep.write(*reportFile); nil != err {
+				fmt.Fprintf(os.Stderr, "error %s\n", err)
+			}
+		}
+	}
+
+	var queued int64
+	if !*daemon {
+		js, _ := seeds(ids)
+		rep := run(context.Background(), js, scrape.IncrementalNows(scrape.Now()), *timeout, *workers, writer(), &queued)
+		finish(rep)
+		if "" != rep.Error {
+			os.Exit(1)
+		}
+		return
+	}
+
+	m := newMetrics(&queued)
+	scrape.OnResponse = m.response
+	if "" != *addr {
+		http.Handle("/metrics", m)
+		go func() {
+			if err := http.ListenAndServe(*addr, nil); nil != err {
+				fmt.Fprintf(os.Stderr, "error %s\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	runTimeout := *timeout
+	if *every < runTimeout {
+		runTimeout = *every
+	}
+	for {
+		t0 := time.Now()
+		js, _ := seeds(ids)
+		rep := run(ctx, js, scrape.IncrementalNows(scrape.Now()), runTimeout, *workers, writer(), &queued)
+		m.run(rep)
+		finish(rep)
+		prune()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(t0.Add(*every))):
+		}
+	}
 }
@@ -0,0 +1,149 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+// The built-in defaults shouldn't drift from htdocs/stations.
+func TestBuiltinStationsMatchHtdocs(t *testing.T) {
+	for _, builtin := range scrape.Stations() {
+		st, err := scrape.LoadStation(filepath.Join("..", "..", "htdocs", "stations", builtin.Identifier))
+		assert.Nil(t, err, builtin.Identifier)
+		assert.Equal(t, builtin.ProgramURL.String(), st.ProgramURL.String(), builtin.Identifier)
+		assert.Equal(t, builtin.CloseDown, st.CloseDown, builtin.Identifier)
+		assert.Equal(t, builtin.TimeZone.String(), st.TimeZone.String(), builtin.Identifier)
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	js, err := seeds([]string{"all"})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, len(scrape.Stations()), len(js), "ouch")
+
+	js, err = seeds([]string{"b2", "wdr5"})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(js), "ouch")
+
+	_, err = seeds([]string{"b2", "nope"})
+	assert.Equal(t, "unknown station: nope", err.Error(), "ouch")
+}
+
+func TestDebug(t *testing.T) {
+	day := time.Date(2015, time.October, 21, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := debug(&buf, "b2", "day", "../scrape/br/testdata/2015-10-21-b2-program.html", day)
+	assert.Nil(t, err, "ouch")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 129, len(lines), "jobs only")
+	assert.Equal(t, "job *br.broadcastURL 2015-10-20T05:00:00+02:00 http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-471766.html 'Nachrichten, Wetter, Verkehr'", lines[0], "ouch")
+
+	buf.Reset()
+	err = debug(&buf, "b2", "broadcast", "../scrape/br/testdata/2015-10-21T0012-b2-sendung.html", day)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, strings.HasPrefix(buf.String(), "{\n  \""), "pretty json")
+	assert.Contains(t, buf.String(), "Concerto bavarese", "ouch")
+
+	err = debug(&buf, "b2", "week", "http://www.br.de/", day)
+	assert.Equal(t, "unknown level 'week' for b2, try one of: broadcast, day, station", err.Error(), "ouch")
+	err = debug(&buf, "b2", "day", "no/such/file", day)
+	assert.Equal(t, "neither a file nor an absolute url: no/such/file", err.Error(), "ouch")
+}
+
+func TestCheck(t *testing.T) {
+	defer func(c *http.Client, n func() time.Time) { scrape.Client, scrape.Now = c, n }(scrape.Client, scrape.Now)
+	fx := &scrape.Fixtures{Dir: filepath.Join("..", "scrape", "m945", "testdata", "fixtures")}
+	recorded, err := fx.Recorded()
+	assert.Nil(t, err, "ouch")
+	scrape.Client = &http.Client{Transport: fx}
+	check1 := func(now time.Time) (bool, string) {
+		scrape.Now = func() time.Time { return now }
+		js, _ := seeds([]string{"m945"})
+		var buf bytes.Buffer
+		ok := check(&buf, js, now, time.Minute, 2)
+		return ok, buf.String()
+	}
+
+	ok, out := check1(recorded)
+	assert.True(t, ok, out)
+	assert.Equal(t, "check m945        ok       10 broadcasts 2015-11-14 00:00 - 2015-11-15 00:00\n", out, "ouch")
+
+	ok, out = check1(recorded.AddDate(0, 0, 2))
+	assert.False(t, ok, "no fixtures for that day")
+	assert.Equal(t, "check m945        FAILED    2 problems\n"+
+		"      5 http errors, 0 parse errors\n"+
+		"      no broadcasts 2015-11-16 00:00 - 2015-11-17 00:00\n", out, "ouch")
+}
+
+// Scrapes a broadcast or panics.
+type faultyScraper string
+
+func (s faultyScraper) Matches(nows []time.Time) bool { return true }
+
+func (s faultyScraper) Scrape(ctx context.Context) ([]scrape.Scraper, []scrape.Broadcaster, error) {
+	if "" == s {
+		panic("How can the identifier miss?")
+	}
+	bc := scrape.Broadcast{}
+	bc.Station.Identifier = string(s)
+	bc.Title = "survived"
+	return nil, []scrape.Broadcaster{bc}, nil
+}
+
+func TestRunRecovers(t *testing.T) {
+	bad, good := scrape.NewStats("bad"), scrape.NewStats("good")
+	var titles []string
+	write := func(b scrape.Broadcaster, _ io.Writer) error {
+		bc, _ := scrape.AsBroadcast(b)
+		titles = append(titles, bc.Station.Identifier+" "+bc.Title)
+		return nil
+	}
+	var queued int64
+	rep := run(context.Background(), []job{{faultyScraper(""), bad}, {faultyScraper("good"), good}}, nil, time.Minute, 2, write, &queued)
+	assert.Equal(t, "", rep.Error, "ouch")
+	assert.Equal(t, []string{"good survived"}, titles, "ouch")
+	assert.Equal(t, 1, bad.Snapshot().ParseErrors, "ouch")
+	assert.Equal(t, 0, good.Snapshot().ParseErrors, "ouch")
+}
+
+func TestRunMerges(t *testing.T) {
+	var n int
+	write := func(b scrape.Broadcaster, _ io.Writer) error { n++; return nil }
+	var queued int64
+	st := scrape.NewStats("good")
+	rep := run(context.Background(), []job{{faultyScraper("good"), st}, {faultyScraper("good"), st}}, nil, time.Minute, 2, write, &queued)
+	assert.Equal(t, 1, n, "same station and start")
+	assert.Equal(t, 1, rep.Duplicates, "ouch")
+	assert.Equal(t, 0, len(rep.Conflicts), "ouch")
+}
@@ -24,6 +24,7 @@
 package b3
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,7 +48,7 @@ func Station(identifier string) *station {
 	switch identifier {
 	case
 		"b3":
-		s := station(r.Station{Name: "Bayern 3", CloseDown: "00:00", ProgramURL: r.MustParseURL("http://www.br.de/mediathek/audio/bayern3-audio-livestream-100~radioplayer.json"), Identifier: identifier, TimeZone: localLoc})
+		s := station(r.Station{Name: "Bayern 3", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/mediathek/audio/bayern3-audio-livestream-100~radioplayer.json"), Identifier: identifier, TimeZone: localLoc})
 		return &s
 	}
 	return nil
@@ -62,9 +63,9 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // queue one scrape job: now!
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
 	i := calItemRangeURL(r.TimeURL{
-		Time:    time.Now(),
+		Time:    r.Now(),
 		Source:  *s.ProgramURL,
 		Station: r.Station(*s),
 	})
@@ -80,8 +81,8 @@ func (bcu *calItemRangeURL) Matches(nows []time.Time) (ok bool) {
 	return true
 }
 
-func (bcu *calItemRangeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := bcu.parseBroadcasts()
+func (bcu *calItemRangeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := bcu.parseBroadcasts(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			results = append(results, bc)
@@ -100,6 +101,8 @@ func init() {
 	if nil != err {
 		panic(err)
 	}
+	r.Register(r.Station(*Station("b3")), func(st r.Station) r.Scraper { s := station(st); return &s })
+	r.RegisterLevel("b3", "day", func(tu r.TimeURL) r.Scraper { d := calItemRangeURL(tu); return &d })
 }
 
 /////////////////////////////////////////////////////////////////////////////
@@ -180,13 +183,13 @@ func (bcu *calItemRangeURL) parseBroadcastsReader(read io.Reader, cr0 *r.Countin
 	err = json.NewDecoder(cr).Decode(&f)
 	r.ReportLoad("🐦", cr0, cr, bcu.Source)
 	if nil != err {
-		return
+		return nil, &r.ParseError{URL: bcu.Source, Err: err}
 	}
 	return bcu.parseBroadcastsFromData(f)
 }
 
-func (bcu *calItemRangeURL) parseBroadcasts() (bc []r.Broadcast, err error) {
-	return r.GenericParseBroadcastFromURL(bcu.Source, func(r io.Reader, cr *r.CountingReader) ([]r.Broadcast, error) {
+func (bcu *calItemRangeURL) parseBroadcasts(ctx context.Context) (bc []r.Broadcast, err error) {
+	return r.GenericParseBroadcastFromURL(ctx, bcu.Source, func(r io.Reader, cr *r.CountingReader) ([]r.Broadcast, error) {
 		return bcu.parseBroadcastsReader(r, cr)
 	})
 }
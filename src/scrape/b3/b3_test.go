@@ -36,6 +36,17 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", b3.TimeZone.String(), "foo")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("b3"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 3, len(bcs), "ouch")
+	first := bcs[0]
+	assert.Equal(t, "b3", first.Station.Identifier, "ouch")
+	assert.Equal(t, "Die Frühaufdreher", first.Title, "ouch")
+	assert.Equal(t, "2016-07-25T05:00:00+02:00", first.Time.Format(time.RFC3339), "ouch")
+}
+
 func TestUnmarshalBroadcasts0(t *testing.T) {
 	f, err := os.Open("testdata/2016-07-25T0945-program.json")
 	assert.NotNil(t, f, "ouch")
@@ -24,6 +24,7 @@
 package b4
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -50,10 +51,12 @@ type station r.Station
 //
 // Returns a instance conforming to 'scrape.Scraper'
 func Station(identifier string) *station {
+	// calendar plus a detail page per broadcast, go easy on www.br-klassik.de
+	limit := &r.HostLimit{Concurrency: 2, Interval: 250 * time.Millisecond}
 	switch identifier {
 	case
 		"b4":
-		s := station(r.Station{Name: "Bayern 4", CloseDown: "06:00", ProgramURL: r.MustParseURL("https://www.br-klassik.de/programm/radio/index.html"), Identifier: identifier, TimeZone: localLoc})
+		s := station(r.Station{Name: "Bayern 4", CloseDown: "06:00", ProgramURL: r.MustParseURL("https://www.br-klassik.de/programm/radio/index.html"), Identifier: identifier, TimeZone: localLoc, Limit: limit})
 		return &s
 	}
 	return nil
@@ -68,10 +71,13 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // Synthesise calItemRangeURLs for incremental scraping and queue them up
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	now := time.Now()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	now := r.Now()
 	for _, t0 := range r.IncrementalNows(now) {
-		u, _ := s.calendarItemRangeURLForTime(t0)
+		u, err := s.calendarItemRangeURLForTime(t0)
+		if nil != err {
+			return nil, nil, err
+		}
 		jobs = append(jobs, r.Scraper(u))
 	}
 	return
@@ -82,13 +88,17 @@ func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error
 // https://www.br-klassik.de/programm/radio/radiosendungen-100~calendarItems.jsp?from=2016-01-10T23:59:59&to=2016-01-11T00:10:00&rows=800
 func (s *station) calendarItemRangeURLForTime(t time.Time) (ret *calItemRangeURL, err error) {
 	if nil == s {
-		panic("aua")
+		return nil, errors.New("no station")
 	}
 	t0 := t.Add(time.Minute)
 	t1 := t0.Add(time.Hour)
+	u, err := url.Parse("https://www.br-klassik.de/programm/radio/radiosendungen-100~calendarItems.jsp?rows=800" + t0.Format("&from=2006-01-02T15:04:05") + t1.Format("&to=2006-01-02T15:04:05"))
+	if nil != err {
+		return
+	}
 	r := calItemRangeURL(r.TimeURL{
 		Time:    t0,
-		Source:  *r.MustParseURL("https://www.br-klassik.de/programm/radio/radiosendungen-100~calendarItems.jsp?rows=800" + t0.Format("&from=2006-01-02T15:04:05") + t1.Format("&to=2006-01-02T15:04:05")),
+		Source:  *u,
 		Station: r.Station(*s),
 	})
 	ret = &r
@@ -101,8 +111,8 @@ func (s *station) calendarItemRangeURLForTime(t time.Time) (ret *calItemRangeURL
 type calItemRangeURL r.TimeURL
 
 // Fetch calendarItems in given interval (via json)
-func (rangeURL *calItemRangeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	calendarItems, err := rangeURL.parseCalendarItems()
+func (rangeURL *calItemRangeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	calendarItems, err := rangeURL.parseCalendarItems(ctx)
 	if nil != err {
 		return
 	}
@@ -119,12 +129,13 @@ func (rangeURL *calItemRangeURL) Matches(nows []time.Time) (ok bool) {
 	return true
 }
 
-func (rangeURL *calItemRangeURL) parseCalendarItems() (cis []calendarItem, err error) {
+func (rangeURL *calItemRangeURL) parseCalendarItems(ctx context.Context) (cis []calendarItem, err error) {
 	// fmt.Fprintf(os.Stderr, "GET %s\n", rangeURL.Source.String())
-	bo, cr, err := r.HttpGetBody(rangeURL.Source)
+	bo, cr, err := r.HttpGetBody(ctx, rangeURL.Source)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return rangeURL.parseCalendarItemsReader(bo, cr)
 }
 
@@ -134,7 +145,7 @@ func (rangeURL *calItemRangeURL) parseCalendarItemsReader(read io.Reader, cr0 *r
 	err = json.NewDecoder(cr).Decode(&cis)
 	r.ReportLoad("🐦", cr0, cr, rangeURL.Source)
 	if nil != err {
-		return
+		return nil, &r.ParseError{URL: rangeURL.Source, Err: err}
 	}
 	for i := range cis {
 		cis[i].Station = &rangeURL.Station
@@ -166,6 +177,9 @@ func init() {
 	if nil != err {
 		panic(err)
 	}
+	r.Register(r.Station(*Station("b4")), func(st r.Station) r.Scraper { s := station(st); return &s })
+	r.RegisterLevel("b4", "day", func(tu r.TimeURL) r.Scraper { d := calItemRangeURL(tu); return &d })
+	r.RegisterLevel("b4", "broadcast", func(tu r.TimeURL) r.Scraper { return &broadcastURL{BroadcastURL: r.BroadcastURL{TimeURL: tu}} })
 }
 
 /////////////////////////////////////////////////////////////////////////////
@@ -218,8 +232,8 @@ func (bcu *broadcastURL) Matches(nows []time.Time) (ok bool) {
 	return true
 }
 
-func (bcu *broadcastURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bc, err := bcu.parseBroadcast()
+func (bcu *broadcastURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bc, err := bcu.parseBroadcast(ctx)
 	if nil == err {
 		results = append(results, bc)
 	}
@@ -237,7 +251,8 @@ var (
 func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, err error) {
 	bc.Station = bcu.Station
 	if "" == bc.Station.Identifier {
-		panic("How can the identifier miss?")
+		err = &r.ValidationError{Source: bcu.Source, Field: "station", Msg: "no identifier"}
+		return
 	}
 	bc.Source = bcu.Source
 	bc.Time = bcu.Time
@@ -249,7 +264,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 
 	for i, main := range scrape.FindAll(root, func(n *html.Node) bool { return atom.Div == n.DataAtom && "br-main-text" == scrape.Attr(n, "class") }) {
 		if 1 < i {
-			err = errors.New("unexpected 2nd <div class='br-main-text'> ")
+			err = r.NewParseError(bcu.Source, main, errors.New("unexpected 2nd <div class='br-main-text'> "))
 			return
 		}
 
@@ -259,7 +274,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 		}) {
 			// fmt.Fprintf(os.Stderr, "GET %s\n", "uhu")
 			if idx != 0 {
-				err = errors.New("There was more than 1 <h3>Weitere Informationen")
+				err = r.NewParseError(bcu.Source, h3, errors.New("There was more than 1 <h3>Weitere Informationen"))
 				return
 			}
 			for _, a := range scrape.FindAll(h3.Parent, func(n *html.Node) bool {
@@ -274,12 +289,12 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 
 		for i1, h2 := range scrape.FindAll(main, func(n *html.Node) bool { return atom.H2 == n.DataAtom }) {
 			if 1 < i1 {
-				err = errors.New("unexpected 2nd <h2> ")
+				err = r.NewParseError(bcu.Source, h2, errors.New("unexpected 2nd <h2> "))
 				return
 			}
 			for i4, em := range scrape.FindAll(h2, func(n *html.Node) bool { return atom.Em == n.DataAtom }) {
 				if 1 < i4 {
-					err = errors.New("unexpected 2nd <em> ")
+					err = r.NewParseError(bcu.Source, em, errors.New("unexpected 2nd <em> "))
 					return
 				}
 				bc.Title = scrape.Text(em)
@@ -290,7 +305,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 
 			for i2, h3 := range scrape.FindAll(main, func(n *html.Node) bool { return atom.H3 == n.DataAtom }) {
 				if 1 < i2 {
-					err = errors.New("unexpected 2nd <h3> ")
+					err = r.NewParseError(bcu.Source, h3, errors.New("unexpected 2nd <h3> "))
 					return
 				}
 				s := scrape.Text(h3)
@@ -317,7 +332,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 	for _, p := range scrape.FindAll(root, func(n *html.Node) bool { return atom.P == n.DataAtom && "br-time" == scrape.Attr(n, "class") }) {
 		m := bcDateRegExp.FindStringSubmatch(scrape.Text(p))
 		if nil == m {
-			err = errors.New("There was no date match")
+			err = r.NewParseError(bcu.Source, p, errors.New("There was no date match"))
 			return
 		}
 		i := r.MustParseInt
@@ -334,7 +349,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 		return atom.Meta == n.DataAtom && "og:article:modified_time" == scrape.Attr(n, "property")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <meta property='og:article:modified_time'/>")
+			err = r.NewParseError(bcu.Source, meta, errors.New("There was more than 1 <meta property='og:article:modified_time'/>"))
 			return
 		}
 		v, _ := time.Parse(time.RFC3339, scrape.Attr(meta, "content"))
@@ -346,13 +361,15 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bc r.Broadcast, er
 		return atom.Meta == n.DataAtom && "author" == scrape.Attr(n, "name")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <meta name='author'/>")
+			err = r.NewParseError(bcu.Source, meta, errors.New("There was more than 1 <meta name='author'/>"))
 			return
 		}
 		s := scrape.Attr(meta, "content")
 		bc.Author = &s
 	}
 
+	// schema.org JSON-LD, where given, beats the above
+	bc = r.PreferJSONLD(root, bc, bcu.Time)
 	return
 }
 
@@ -366,10 +383,11 @@ func (bcu *broadcastURL) parseBroadcastReader(read io.Reader, cr0 *r.CountingRea
 	return bcu.parseBroadcastNode(root)
 }
 
-func (bcu *broadcastURL) parseBroadcast() (bc r.Broadcast, err error) {
-	bo, cr, err := r.HttpGetBody(bcu.Source)
+func (bcu *broadcastURL) parseBroadcast(ctx context.Context) (bc r.Broadcast, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, bcu.Source)
 	if nil == bo {
 		return bc, err
 	}
+	defer bo.Close()
 	return bcu.parseBroadcastReader(bo, cr)
 }
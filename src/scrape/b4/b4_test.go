@@ -24,7 +24,9 @@ package b4 // import "purl.mro.name/recorder/radio/scrape/b4"
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +39,17 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", b4.TimeZone.String(), "foo")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("b4"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(bcs), "ouch")
+	first := bcs[0]
+	assert.Equal(t, "b4", first.Station.Identifier, "ouch")
+	assert.Equal(t, "Intermezzo", first.Title, "ouch")
+	assert.Equal(t, "2015-11-30T05:00:00+01:00", first.Time.Format(time.RFC3339), "ouch")
+}
+
 func TestDayURLForDate(t *testing.T) {
 	s := Station("b4")
 	u, err := s.calendarItemRangeURLForTime(time.Date(2015, 11, 30, 5, 6, 7, 8, s.TimeZone))
@@ -159,6 +172,40 @@ func TestParseBroadcast_914548(t *testing.T) {
 	assert.Equal(t, "https://www.br-klassik.de/programm/radio/logo106~_h-558_v-img__16__9__xl_w-994_-e1d284d92729d9396a907e303225e0f2d9fa53b4.jpg?version=78f3c", bc.Image.String(), "ouch: Image")
 }
 
+// schema.org JSON-LD, where given, beats the HTML. Ours is the date at bcu.Time.
+func TestParseBroadcastJSONLD(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/2016-11-27T2030-b4-ausstrahlung-914548.html")
+	assert.Nil(t, err, "ouch")
+	ld := `<script type="application/ld+json">{"@context": "http://schema.org", "@type": "RadioEpisode",
+	"name": "Intermezzo", "description": "Ernst von Gemmingen: Violinkonzert Nr. 1 A-Dur",
+	"partOfSeries": {"@type": "RadioSeries", "name": "Konzert"},
+	"publication": [
+		{"@type": "BroadcastEvent", "startDate": "2016-11-20T20:30:00+01:00", "endDate": "2016-11-20T21:00:00+01:00"},
+		{"@type": "BroadcastEvent", "startDate": "2016-11-27T20:30:00+01:00", "endDate": "2016-11-27T21:05:00+01:00"}
+	]}</script></head>`
+	htm := strings.Replace(string(data), "</head>", ld, 1)
+
+	s := Station("b4")
+	t0 := broadcastURL{
+		BroadcastURL: r.BroadcastURL{
+			TimeURL: r.TimeURL{
+				Time:    time.Date(2016, time.November, 27, 20, 30, 0, 0, localLoc),
+				Source:  *r.MustParseURL("https://www.br-klassik.de/programm/radio/ausstrahlung-914548.html"),
+				Station: r.Station(*s),
+			},
+			Title: "Intermezzo",
+		},
+	}
+	bc, err := t0.parseBroadcastReader(strings.NewReader(htm), nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "Intermezzo", bc.Title, "ouch: Title")
+	assert.Equal(t, "Konzert", *bc.TitleSeries, "JSON-LD")
+	assert.Equal(t, "Ernst von Gemmingen: Violinkonzert Nr. 1 A-Dur", *bc.Description, "JSON-LD")
+	assert.Equal(t, "2016-11-27T20:30:00+01:00", bc.Time.Format(time.RFC3339), "ours")
+	assert.Equal(t, "2016-11-27T21:05:00+01:00", bc.DtEnd.Format(time.RFC3339), "JSON-LD")
+	assert.Equal(t, "2016-11-25T13:15:07+01:00", bc.Modified.Format(time.RFC3339), "HTML")
+}
+
 func TestParseBroadcast_866264(t *testing.T) {
 	{
 		t0, _ := time.Parse(time.RFC3339, "2016-11-27T23:05:00+01:00")
@@ -24,11 +24,13 @@
 package br
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,18 +55,29 @@ func Station(identifier string) *station {
 	if nil != err {
 		panic(err)
 	}
+	// detail pages come in dozens, go easy on www.br.de. station.cfg may
+	// override it with host_concurrency and host_interval, see r.LoadStations.
+	limit := &r.HostLimit{Concurrency: 2, Interval: 250 * time.Millisecond}
 	s := map[string]station{
-		"b+":       station(r.Station{Name: "Bayern Plus", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern-plus/programmkalender/bayern-plus114.html"), Identifier: identifier, TimeZone: tz}),
-		"b1":       station(r.Station{Name: "Bayern 1", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern1/service/programm/index.html"), Identifier: identifier, TimeZone: tz}),
-		"b2":       station(r.Station{Name: "Bayern 2", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern2/service/programm/index.html"), Identifier: identifier, TimeZone: tz}),
-		"b5":       station(r.Station{Name: "Bayern 5", CloseDown: "06:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/b5-aktuell/programmkalender/b5aktuell116.html"), Identifier: identifier, TimeZone: tz}),
-		"brheimat": station(r.Station{Name: "BR Heimat", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/br-heimat/programmkalender/br-heimat-116.html"), Identifier: identifier, TimeZone: tz}),
-		"puls":     station(r.Station{Name: "Puls", CloseDown: "07:00", ProgramURL: r.MustParseURL("http://www.br.de/puls/programm/puls-radio/programmkalender/programmfahne104.html"), Identifier: identifier, TimeZone: tz}),
+		"b+":       station(r.Station{Name: "Bayern Plus", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern-plus/programmkalender/bayern-plus114.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
+		"b1":       station(r.Station{Name: "Bayern 1", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern1/service/programm/index.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
+		"b2":       station(r.Station{Name: "Bayern 2", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/bayern2/service/programm/index.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
+		"b5":       station(r.Station{Name: "Bayern 5", CloseDown: "06:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/b5-aktuell/programmkalender/b5aktuell116.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
+		"brheimat": station(r.Station{Name: "BR Heimat", CloseDown: "05:00", ProgramURL: r.MustParseURL("http://www.br.de/radio/br-heimat/programmkalender/br-heimat-116.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
+		"puls":     station(r.Station{Name: "Puls", CloseDown: "07:00", ProgramURL: r.MustParseURL("http://www.br.de/puls/programm/puls-radio/programmkalender/programmfahne104.html"), Identifier: identifier, TimeZone: tz, Limit: limit}),
 	}[identifier]
 	// fmt.Fprintf(os.Stderr, "             %p %s\n", &s, s.Name)
 	return &s
 }
 
+func init() {
+	for _, id := range []string{"b+", "b1", "b2", "b5", "brheimat", "puls"} {
+		r.Register(r.Station(*Station(id)), func(st r.Station) r.Scraper { s := station(st); return &s })
+		r.RegisterLevel(id, "day", func(tu r.TimeURL) r.Scraper { d := timeURL(tu); return &d })
+		r.RegisterLevel(id, "broadcast", func(tu r.TimeURL) r.Scraper { return &broadcastURL{TimeURL: tu} })
+	}
+}
+
 func (s *station) String() string {
 	return fmt.Sprintf("Station '%s'", s.Name)
 }
@@ -99,17 +112,18 @@ func (s *station) parseDayURLsReader(read io.Reader, cr0 *r.CountingReader) (ret
 	return
 }
 
-func (s *station) parseDayURLs() (ret []timeURL, err error) {
-	bo, cr, err := r.HttpGetBody(*s.ProgramURL)
+func (s *station) parseDayURLs(ctx context.Context) (ret []timeURL, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, *s.ProgramURL)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return s.parseDayURLsReader(bo, cr)
 }
 
 // Scrape slice of timeURL - all calendar (day) entries of the station program url
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	dayUrls, err := s.parseDayURLs()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	dayUrls, err := s.parseDayURLs(ctx)
 	if nil == err {
 		for _, v := range dayUrls {
 			vv := v
@@ -144,7 +158,7 @@ func (s *station) newTimeURL(relUrl string) (ret r.TimeURL, err error) {
 	programURL := *s.ProgramURL.ResolveReference(ru)
 	ret = r.TimeURL{Time: day, Source: programURL, Station: r.Station(*s)}
 	if "" == ret.Station.Identifier {
-		panic("How can the identifier miss?")
+		err = &r.ValidationError{Source: programURL, Field: "station", Msg: "no identifier"}
 	}
 	// fmt.Fprintf(os.Stderr, " t %s %s\n", ret.Time.Format(time.RFC3339), ret.Source.String())
 	return
@@ -155,8 +169,8 @@ func (s *station) newTimeURL(relUrl string) (ret r.TimeURL, err error) {
 type timeURL r.TimeURL
 
 // Scrape slice of broadcastURL - all per-day broadcast entries of the day url
-func (day *timeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	broadcastUrls, err := day.parseBroadcastURLs()
+func (day *timeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	broadcastUrls, err := day.parseBroadcastURLs(ctx)
 	if nil == err {
 		for _, b := range broadcastUrls {
 			bb := *b
@@ -185,16 +199,26 @@ func (day *timeURL) parseBroadcastURLsNode(root *html.Node) (ret []*broadcastURL
 	for _, h4 := range scrape.FindAll(root, func(n *html.Node) bool { return atom.H4 == n.DataAtom }) {
 		year, month, day2, err := timeForH4(scrape.Text(h4), &day.Time)
 		if nil != err {
-			panic(err)
+			return nil, r.NewParseError(day.Source, h4, err)
 		}
 		// fmt.Printf("%d-%d-%d %s\n", year, month, day, err)
 		for _, a := range scrape.FindAll(h4.Parent, func(n *html.Node) bool { return atom.A == n.DataAtom && atom.Dt == n.Parent.DataAtom }) {
 			m := hourMinuteTitleRegExp.FindStringSubmatch(scrape.Text(a))
 			if nil == m {
-				panic(errors.New("Couldn't parse <a>"))
+				return nil, r.NewParseError(day.Source, a, errors.New("Couldn't parse <a>"))
+			}
+			ur, err := url.Parse(scrape.Attr(a, "href"))
+			if nil != err {
+				return nil, r.NewParseError(day.Source, a, err)
+			}
+			hour, err := r.ParseInt(day.Source, m[1])
+			if nil != err {
+				return nil, err
+			}
+			minute, err := r.ParseInt(day.Source, m[2])
+			if nil != err {
+				return nil, err
 			}
-			ur, _ := url.Parse(scrape.Attr(a, "href"))
-			hour := r.MustParseInt(m[1])
 			dayOffset := 0
 			if hour < closeDownHour {
 				dayOffset = 1
@@ -202,7 +226,7 @@ func (day *timeURL) parseBroadcastURLsNode(root *html.Node) (ret []*broadcastURL
 			// fmt.Printf("%s %s\n", b.r.TimeURL.String(), b.Title)
 			bcu := broadcastURL(r.BroadcastURL{
 				TimeURL: r.TimeURL{
-					Time:    time.Date(year, month, day2+dayOffset, hour, r.MustParseInt(m[2]), 0, 0, localLoc),
+					Time:    time.Date(year, month, day2+dayOffset, hour, minute, 0, 0, localLoc),
 					Source:  *day.Source.ResolveReference(ur),
 					Station: day.Station,
 				},
@@ -224,11 +248,12 @@ func (day *timeURL) parseBroadcastURLsReader(read io.Reader, cr0 *r.CountingRead
 	return day.parseBroadcastURLsNode(root)
 }
 
-func (day *timeURL) parseBroadcastURLs() (ret []*broadcastURL, err error) {
-	bo, cr, err := r.HttpGetBody(day.Source)
+func (day *timeURL) parseBroadcastURLs(ctx context.Context) (ret []*broadcastURL, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, day.Source)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return day.parseBroadcastURLsReader(bo, cr)
 }
 
@@ -238,8 +263,8 @@ func (day *timeURL) parseBroadcastURLs() (ret []*broadcastURL, err error) {
 /// Just wrap BroadcastURL into a distinct, local type.
 type broadcastURL r.BroadcastURL
 
-func (bcu *broadcastURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := bcu.parseBroadcastsFromURL()
+func (bcu *broadcastURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := bcu.parseBroadcastsFromURL(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			results = append(results, bc)
@@ -306,9 +331,13 @@ func timeForH4(h4 string, now *time.Time) (year int, mon time.Month, day int, er
 		// err = error.New("Couldn't parse " + h4)
 		return
 	}
-	mon = time.Month(r.MustParseInt(m[2]))
+	mo, err := strconv.Atoi(m[2])
+	if nil != err {
+		return
+	}
+	mon = time.Month(mo)
 	year = yearForMonth(mon, now)
-	day = r.MustParseInt(m[1])
+	day, err = strconv.Atoi(m[1])
 	return
 }
 
@@ -332,7 +361,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 	// Title, TitleSeries, TitleEpisode
 	for i, h1 := range scrape.FindAll(root, func(n *html.Node) bool { return atom.H1 == n.DataAtom && "bcast_headline" == scrape.Attr(n, "class") }) {
 		if i != 0 {
-			err = errors.New("There was more than 1 <h1 class='bcast_headline'>")
+			err = r.NewParseError(bcu.Source, h1, errors.New("There was more than 1 <h1 class='bcast_headline'>"))
 			return
 		}
 		bc.Title = r.TextChildrenNoClimb(h1)
@@ -345,7 +374,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 				s := scrape.Text(span)
 				bc.TitleEpisode = &s
 			default:
-				err = errors.New("unexpected <span> inside <h1>")
+				err = r.NewParseError(bcu.Source, span, errors.New("unexpected <span> inside <h1>"))
 				return
 			}
 			bc.Title = r.TextChildrenNoClimb(h1)
@@ -384,12 +413,12 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 	// Time, DtEnd
 	for idx, p := range scrape.FindAll(root, func(n *html.Node) bool { return atom.P == n.DataAtom && "bcast_date" == scrape.Attr(n, "class") }) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <p class='bcast_date'>")
+			err = r.NewParseError(bcu.Source, p, errors.New("There was more than 1 <p class='bcast_date'>"))
 			return
 		}
 		m := bcDateRegExp.FindStringSubmatch(scrape.Text(p))
 		if nil == m {
-			err = errors.New("There was no date match")
+			err = r.NewParseError(bcu.Source, p, errors.New("There was no date match"))
 			return
 		}
 		i := r.MustParseInt
@@ -406,10 +435,15 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 		return atom.Meta == n.DataAtom && "og:locale" == scrape.Attr(n, "property")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <meta property='og:locale'/>")
+			err = r.NewParseError(bcu.Source, meta, errors.New("There was more than 1 <meta property='og:locale'/>"))
 			return
 		}
-		v := scrape.Attr(meta, "content")[0:2]
+		v := scrape.Attr(meta, "content")
+		if len(v) < 2 {
+			err = r.NewParseError(bcu.Source, meta, errors.New("og:locale too short: '"+v+"'"))
+			return
+		}
+		v = v[0:2]
 		bc.Language = &v
 	}
 
@@ -418,7 +452,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 		return atom.A == n.DataAtom && strings.HasPrefix(scrape.Attr(n, "class"), "link_broadcast media_broadcastSeries")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <a class='link_broadcast media_broadcastSeries'/>")
+			err = r.NewParseError(bcu.Source, a, errors.New("There was more than 1 <a class='link_broadcast media_broadcastSeries'/>"))
 			return
 		}
 		u, _ := url.Parse(scrape.Attr(a, "href"))
@@ -430,7 +464,7 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 		return atom.Meta == n.DataAtom && "og:article:modified_time" == scrape.Attr(n, "property")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <meta property='og:article:modified_time'/>")
+			err = r.NewParseError(bcu.Source, meta, errors.New("There was more than 1 <meta property='og:article:modified_time'/>"))
 			return
 		}
 		v, _ := time.Parse(time.RFC3339, scrape.Attr(meta, "content"))
@@ -442,15 +476,17 @@ func (bcu *broadcastURL) parseBroadcastNode(root *html.Node) (bcs []r.Broadcast,
 		return atom.Meta == n.DataAtom && "author" == scrape.Attr(n, "name")
 	}) {
 		if idx != 0 {
-			err = errors.New("There was more than 1 <meta name='author'/>")
+			err = r.NewParseError(bcu.Source, meta, errors.New("There was more than 1 <meta name='author'/>"))
 			return
 		}
 		s := scrape.Attr(meta, "content")
 		bc.Author = &s
 	}
 
-	if "" == bc.Station.Identifier {
-		panic("How can the identifier miss?")
+	// schema.org JSON-LD, where given, beats the above
+	bc = r.PreferJSONLD(root, bc, bcu.Time)
+	if err = bc.Validate(); nil != err {
+		return
 	}
 	bcs = append(bcs, bc)
 	return
@@ -466,8 +502,8 @@ func (bcu *broadcastURL) parseBroadcastReader(read io.Reader, cr0 *r.CountingRea
 	return bcu.parseBroadcastNode(root)
 }
 
-func (bcu *broadcastURL) parseBroadcastsFromURL() (bc []r.Broadcast, err error) {
-	return r.GenericParseBroadcastFromURL(bcu.Source, func(r io.Reader, cr *r.CountingReader) ([]r.Broadcast, error) {
+func (bcu *broadcastURL) parseBroadcastsFromURL(ctx context.Context) (bc []r.Broadcast, err error) {
+	return r.GenericParseBroadcastFromURL(ctx, bcu.Source, func(r io.Reader, cr *r.CountingReader) ([]r.Broadcast, error) {
 		return bcu.parseBroadcastReader(r, cr)
 	})
 }
@@ -23,7 +23,10 @@
 package br // import "purl.mro.name/recorder/radio/scrape/br"
 
 import (
+	"bytes"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +87,17 @@ func TestTimeForH4(t *testing.T) {
 	assert.Nil(t, err, "ouch")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("b2"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 5, len(bcs), "ouch")
+	first := bcs[0]
+	assert.Equal(t, "b2", first.Station.Identifier, "ouch")
+	assert.Equal(t, "Notizbuch", first.Title, "ouch")
+	assert.Equal(t, "2015-10-21T10:05:00+02:00", first.Time.Format(time.RFC3339), "ouch")
+}
+
 func TestParseCalendarForDayURLs(t *testing.T) {
 	f, err := os.Open("testdata/2015-10-21-b2-program.html")
 	assert.NotNil(t, f, "ouch")
@@ -119,6 +133,20 @@ func TestParseScheduleForBroadcasts(t *testing.T) {
 	assert.Equal(t, "2015-10-23T04:58:00+02:00", a[128].Time.Format(time.RFC3339), "ouch: ")
 }
 
+func TestParseScheduleLayoutChanged(t *testing.T) {
+	s := Station("b2")
+	u := timeURL{
+		Time:    time.Date(2015, time.October, 21, 5, 0, 0, 0, localLoc),
+		Source:  *r.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/programmfahne102.html"),
+		Station: r.Station(*s),
+	}
+	_, err := u.parseBroadcastURLsReader(strings.NewReader("<h4>Mittwoch, 21.10.</h4><dl><dt><a href='/x.html'>Mittags</a></dt></dl>"), nil)
+	e, ok := err.(*r.ParseError)
+	assert.True(t, ok, "an error instead of a panic")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/programmkalender/programmfahne102.html", e.URL.String(), "ouch")
+	assert.Equal(t, `<a href="/x.html">Mittags</a>`, e.Node, "ouch")
+}
+
 func TestParseBroadcast_0(t *testing.T) {
 	{
 		t0, _ := time.Parse(time.RFC3339, "2015-10-22T00:06:13+02:00")
@@ -161,6 +189,121 @@ func TestParseBroadcast_0(t *testing.T) {
 	assert.Equal(t, "http://www.br.de/layout/img/programmfahne/concerto-bavarese112~_v-img__16__9__m_-4423061158a17f4152aef84861ed0243214ae6e7.jpg?version=40aa3", bc.Image.String(), "ouch: Image")
 }
 
+// schema.org JSON-LD, where given, beats the HTML
+func TestParseBroadcastJSONLD(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/2015-10-21T0012-b2-sendung.html")
+	assert.Nil(t, err, "ouch")
+	ld := `<script type="application/ld+json">{"@context": "http://schema.org", "@type": "RadioEpisode",
+	"name": "Concerto bavarese", "partOfSeries": {"@type": "RadioSeries", "name": "Aus dem Studio Franken"},
+	"publication": [{"@type": "BroadcastEvent", "startDate": "2015-10-28T00:12:00+01:00"},
+	{"@type": "BroadcastEvent", "startDate": "2015-10-21T00:12:00+02:00", "endDate": "2015-10-21T01:58:00+02:00"}]}</script></head>`
+	htm := strings.Replace(string(data), "</head>", ld, 1)
+
+	s := Station("b2")
+	t0 := broadcastURL{
+		TimeURL: r.TimeURL{
+			Time:    time.Date(2015, time.October, 21, 0, 12, 0, 0, localLoc),
+			Source:  *r.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-472548.html"),
+			Station: r.Station(*s),
+		},
+		Title: "Concerto bavarese",
+	}
+	bcs, err := t0.parseBroadcastReader(strings.NewReader(htm), nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(bcs), "not the repeat a week later")
+	bc := bcs[0]
+	assert.Equal(t, "Concerto bavarese", bc.Title, "ouch: Title")
+	assert.Equal(t, "2015-10-21T00:12:00+02:00", bc.Time.Format(time.RFC3339), "ouch: Time")
+	assert.Equal(t, "Aus dem Studio Franken", *bc.TitleSeries, "JSON-LD")
+	assert.Equal(t, "Fränkische Komponisten", *bc.TitleEpisode, "HTML")
+	assert.Equal(t, "2015-10-21T01:58:00+02:00", bc.DtEnd.Format(time.RFC3339), "JSON-LD")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/musik/concerto-bavarese/index.html", bc.Subject.String(), "HTML")
+	assert.Equal(t, "Bayerischer Rundfunk", *bc.Author, "HTML")
+}
+
+func TestParseBroadcastShortLocale(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/2015-10-21T0012-b2-sendung.html")
+	assert.Nil(t, err, "ouch")
+	htm := strings.Replace(string(data), `<meta property="og:locale" content="de_DE"/>`, `<meta property="og:locale" content="d"/>`, 1)
+
+	s := Station("b2")
+	t0 := broadcastURL{
+		TimeURL: r.TimeURL{
+			Time:    time.Date(2015, time.October, 21, 0, 12, 0, 0, localLoc),
+			Source:  *r.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-472548.html"),
+			Station: r.Station(*s),
+		},
+		Title: "Concerto bavarese",
+	}
+	_, err = t0.parseBroadcastReader(strings.NewReader(htm), nil)
+	assert.NotNil(t, err, "no panic")
+	_, ok := err.(*r.ParseError)
+	assert.True(t, ok, "ouch")
+	assert.True(t, strings.HasSuffix(err.Error(), "og:locale too short: 'd'"), err.Error())
+}
+
+func TestParseBroadcastNoDate(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/2015-10-21T0012-b2-sendung.html")
+	assert.Nil(t, err, "ouch")
+	htm := strings.Replace(string(data), `<p class="bcast_date">`, `<p class="bcast_date">Mittags</p><p>`, 1)
+
+	s := Station("b2")
+	t0 := broadcastURL{
+		TimeURL: r.TimeURL{
+			Time:    time.Date(2015, time.October, 21, 0, 12, 0, 0, localLoc),
+			Source:  *r.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-472548.html"),
+			Station: r.Station(*s),
+		},
+		Title: "Concerto bavarese",
+	}
+	_, err = t0.parseBroadcastReader(strings.NewReader(htm), nil)
+	e, ok := err.(*r.ParseError)
+	assert.True(t, ok, "ouch")
+	assert.Equal(t, `<p class="bcast_date">Mittags</p>`, e.Node, "ouch")
+	assert.True(t, strings.HasSuffix(err.Error(), "There was no date match"), err.Error())
+}
+
+func TestParseBroadcastJSONRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/2015-10-21T0012-b2-sendung.html")
+	assert.NotNil(t, f, "ouch")
+	assert.Nil(t, err, "ouch")
+
+	s := Station("b2")
+	t0 := broadcastURL{
+		TimeURL: r.TimeURL{
+			Time:    time.Date(2015, time.October, 21, 0, 12, 0, 0, localLoc),
+			Source:  *r.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-472548.html"),
+			Station: r.Station(*s),
+		},
+		Title: "Concerto bavarese",
+	}
+	bcs, err := t0.parseBroadcastReader(f, nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(bcs), "ouch")
+	bc0 := bcs[0]
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, bc0.WriteAsJSON(buf), "ouch")
+	res, err := r.ReadBroadcastsJSON(buf)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(res), "ouch")
+	bc := res[0]
+	assert.Equal(t, "b2", bc.Station.Identifier, "ouch: Station.Identifier")
+	assert.Equal(t, bc0.Title, bc.Title, "ouch: Title")
+	assert.Equal(t, bc0.Source.String(), bc.Source.String(), "ouch: Source")
+	assert.Equal(t, *bc0.Language, *bc.Language, "ouch: Language")
+	assert.Equal(t, *bc0.TitleSeries, *bc.TitleSeries, "ouch: TitleSeries")
+	assert.Equal(t, *bc0.TitleEpisode, *bc.TitleEpisode, "ouch: TitleEpisode")
+	assert.Equal(t, "2015-10-21T00:12:00+02:00", bc.Time.Format(time.RFC3339), "ouch: Time")
+	assert.Equal(t, "2015-10-21T02:00:00+02:00", bc.DtEnd.Format(time.RFC3339), "ouch: DtEnd")
+	assert.Equal(t, bc0.Subject.String(), bc.Subject.String(), "ouch: Subject")
+	assert.Equal(t, "2015-10-22T00:06:13+02:00", bc.Modified.Format(time.RFC3339), "ouch: Modified")
+	assert.Equal(t, *bc0.Author, *bc.Author, "ouch: Author")
+	assert.Equal(t, *bc0.Description, *bc.Description, "ouch: Description")
+	assert.Equal(t, bc0.Image.String(), bc.Image.String(), "ouch: Image")
+	assert.Nil(t, bc.Publisher, "Publisher")
+}
+
 func TestParseBroadcast_1(t *testing.T) {
 	f, err := os.Open("testdata/2015-10-21T1005-b2-sendung.html")
 	assert.NotNil(t, f, "ouch")
@@ -388,3 +531,11 @@ func TestParseBroadcast20171207(t *testing.T) {
 	assert.Nil(t, bc.Creator, "Creator")
 	assert.Nil(t, bc.Copyright, "Copyright")
 }
+
+func TestRegistered(t *testing.T) {
+	for _, id := range []string{"b+", "b1", "b2", "b5", "brheimat", "puls"} {
+		s, err := r.NewScraper(id)
+		assert.Nil(t, err, id)
+		assert.Equal(t, id, s.(*station).Identifier, "ouch")
+	}
+}
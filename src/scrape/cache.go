@@ -0,0 +1,229 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// An on-disk cache beneath HttpGetBody using conditional GET requests
+// (If-None-Match/If-Modified-Since), so unchanged pages cost a 304.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Used by HttpGetBody if not nil.
+var Cache *HttpCache
+
+// Bodies are stored as received (maybe gzipped) in <Dir>/xx/<sha1 of url>
+// next to a .json with the validators.
+type HttpCache struct {
+	Dir      string
+	MaxAge   time.Duration // Prune entries not fetched nor revalidated for that long, 0: no limit
+	MaxBytes int64         // Prune the least recently used entries beyond that size, 0: no limit
+}
+
+type cacheEntry struct {
+	URL             string   `json:"url"`
+	ETag            string   `json:"etag,omitempty"`
+	LastModified    string   `json:"last_modified,omitempty"`
+	ContentEncoding []string `json:"content_encoding,omitempty"`
+}
+
+func (c *HttpCache) file(u url.URL) string {
+	sum := sha1.Sum([]byte(u.String()))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(c.Dir, h[:2], h)
+}
+
+func (c *HttpCache) entry(u url.URL) (ret *cacheEntry) {
+	file := c.file(u)
+	data, err := ioutil.ReadFile(file + ".json")
+	if nil != err {
+		return nil
+	}
+	ret = &cacheEntry{}
+	if nil != json.Unmarshal(data, ret) || u.String() != ret.URL {
+		return nil
+	}
+	if "" == ret.ETag && "" == ret.LastModified {
+		return nil
+	}
+	if _, err = os.Stat(file); nil != err {
+		return nil
+	}
+	return
+}
+
+// Add the validators of a cached response to req. Returns nil if there is none.
+func (c *HttpCache) prepare(req *http.Request, u url.URL) *cacheEntry {
+	e := c.entry(u)
+	if nil == e {
+		return nil
+	}
+	if "" != e.ETag {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if "" != e.LastModified {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+	return e
+}
+
+// The stored body, marked as recently used for Prune.
+func (c *HttpCache) body(u url.URL) ([]byte, error) {
+	file := c.file(u)
+	data, err := ioutil.ReadFile(file)
+	if nil == err {
+		now := time.Now()
+		os.Chtimes(file, now, now)
+		os.Chtimes(file+".json", now, now)
+	}
+	return data, err
+}
+
+// Write body and validators, tmp + rename so readers never see half a file.
+func (c *HttpCache) store(u url.URL, header http.Header, body []byte) (err error) {
+	e := cacheEntry{
+		URL:             u.String(),
+		ETag:            header.Get("ETag"),
+		LastModified:    header.Get("Last-Modified"),
+		ContentEncoding: header["Content-Encoding"],
+	}
+	if "" == e.ETag && "" == e.LastModified {
+		return // no way to revalidate
+	}
+	meta, err := json.Marshal(e)
+	if nil != err {
+		return
+	}
+	file := c.file(u)
+	if err = os.MkdirAll(filepath.Dir(file), 0755); nil != err {
+		return
+	}
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{file, body}, {file + ".json", meta}} {
+		if err = ioutil.WriteFile(f.name+"~", f.data, 0644); nil != err {
+			return
+		}
+		if err = os.Rename(f.name+"~", f.name); nil != err {
+			return
+		}
+	}
+	return
+}
+
+// Remove entries older than MaxAge, then the least recently used ones until
+// the rest fits into MaxBytes.
+func (c *HttpCache) Prune(now time.Time) error {
+	type entry struct {
+		file  string
+		size  int64
+		mtime time.Time
+	}
+	var entries []entry
+	total := int64(0)
+	err := filepath.Walk(c.Dir, func(path string, fi os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+		if fi.IsDir() || ".json" != filepath.Ext(path) {
+			return nil
+		}
+		e := entry{file: strings.TrimSuffix(path, ".json"), size: fi.Size(), mtime: fi.ModTime()}
+		if bo, err := os.Stat(e.file); nil == err {
+			e.size += bo.Size()
+		}
+		if 0 < c.MaxAge && now.Sub(e.mtime) > c.MaxAge {
+			return removeEntry(e.file)
+		}
+		entries = append(entries, e)
+		total += e.size
+		return nil
+	})
+	if nil != err || 0 >= c.MaxBytes {
+		return err
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].mtime.Before(entries[b].mtime) })
+	for _, e := range entries {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err = removeEntry(e.file); nil != err {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// validators first, so a body without them is never used
+func removeEntry(file string) error {
+	for _, f := range []string{file + ".json", file} {
+		if err := os.Remove(f); nil != err && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pass a response body through and store it once read completely.
+type cacheTee struct {
+	reader io.Reader
+	buf    bytes.Buffer
+	commit func(body []byte)
+}
+
+func (t *cacheTee) Read(p []byte) (n int, err error) {
+	n, err = t.reader.Read(p)
+	t.buf.Write(p[:n])
+	switch {
+	case nil == t.commit:
+	case io.EOF == err:
+		t.commit(t.buf.Bytes())
+		t.commit = nil
+	case nil != err:
+		t.commit = nil // incomplete, don't store
+	}
+	return
+}
+
+// Most bodies that go unread till EOF are JSON, where the decoder stops
+// after the value, so read the rest (up to a limit) to get them stored.
+const maxCacheDrain = 1 << 20
+
+func (t *cacheTee) drain() {
+	if nil != t.commit {
+		io.CopyN(ioutil.Discard, t, maxCacheDrain)
+	}
+}
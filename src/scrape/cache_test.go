@@ -0,0 +1,186 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpGetBodyCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-cache")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	Cache = &HttpCache{Dir: dir}
+	defer func() { Cache = nil }()
+
+	hits, misses := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			if `"v1"` == r.Header.Get("If-None-Match") {
+				hits++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			misses++
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("<html>etag</html>"))
+			gz.Close()
+		case "/lastmod":
+			if "Thu, 25 Aug 2016 18:05:00 GMT" == r.Header.Get("If-Modified-Since") {
+				hits++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			misses++
+			w.Header().Set("Last-Modified", "Thu, 25 Aug 2016 18:05:00 GMT")
+			w.Write([]byte("<html>lastmod</html>"))
+		default:
+			misses++
+			w.Write([]byte("<html>uncacheable</html>"))
+		}
+	}))
+	defer srv.Close()
+
+	get := func(path string) (string, *CountingReader) {
+		bo, cr, err := HttpGetBody(context.Background(), *MustParseURL(srv.URL + path))
+		assert.Nil(t, err, "ouch")
+		defer bo.Close()
+		data, err := ioutil.ReadAll(bo)
+		assert.Nil(t, err, "ouch")
+		return string(data), cr
+	}
+
+	for _, tc := range []struct{ path, body string }{
+		{"/etag", "<html>etag</html>"},
+		{"/lastmod", "<html>lastmod</html>"},
+	} {
+		hits, misses = 0, 0
+		body, cr := get(tc.path)
+		assert.Equal(t, tc.body, body, tc.path)
+		assert.False(t, cr.Cached, tc.path)
+		assert.True(t, 0 < cr.TotalBytes, tc.path)
+
+		body, cr = get(tc.path)
+		assert.Equal(t, tc.body, body, tc.path)
+		assert.True(t, cr.Cached, tc.path)
+		assert.Equal(t, 1, hits, tc.path)
+		assert.Equal(t, 1, misses, tc.path)
+	}
+
+	hits, misses = 0, 0
+	get("/plain")
+	body, cr := get("/plain")
+	assert.Equal(t, "<html>uncacheable</html>", body, "ouch")
+	assert.False(t, cr.Cached, "no validators, no cache")
+	assert.Equal(t, 0, hits, "ouch")
+	assert.Equal(t, 2, misses, "ouch")
+}
+
+// json.Decoder stops after the value and never sees EOF, Close has to finish.
+func TestHttpGetBodyCacheJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-cache")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	Cache = &HttpCache{Dir: dir}
+	defer func() { Cache = nil }()
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if `"v1"` == r.Header.Get("If-None-Match") {
+			hits++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		// chunked, so the decoder gets the value before the end
+		w.Write([]byte("{\"title\": \"Nachtmix\"}"))
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("\n\n"))
+	}))
+	defer srv.Close()
+
+	get := func() (string, *CountingReader) {
+		bo, cr, err := HttpGetBody(context.Background(), *MustParseURL(srv.URL + "/program.json"))
+		assert.Nil(t, err, "ouch")
+		defer bo.Close()
+		var v struct{ Title string }
+		assert.Nil(t, json.NewDecoder(bo).Decode(&v), "ouch")
+		return v.Title, cr
+	}
+	title, cr := get()
+	assert.Equal(t, "Nachtmix", title, "ouch")
+	assert.False(t, cr.Cached, "ouch")
+	title, cr = get()
+	assert.Equal(t, "Nachtmix", title, "ouch")
+	assert.True(t, cr.Cached, "stored though not read till EOF")
+	assert.Equal(t, 1, hits, "ouch")
+}
+
+func TestHttpCachePrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-cache")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	c := &HttpCache{Dir: dir}
+	now := time.Now()
+	header := http.Header{"Etag": []string{`"v1"`}}
+	for i, age := range []time.Duration{1, 10, 100} {
+		u := *MustParseURL("http://example.com/" + string('a'+rune(i)))
+		assert.Nil(t, c.store(u, header, make([]byte, 1000)), "ouch")
+		file := c.file(u)
+		os.Chtimes(file, now.Add(-age*time.Hour), now.Add(-age*time.Hour))
+		os.Chtimes(file+".json", now.Add(-age*time.Hour), now.Add(-age*time.Hour))
+	}
+	count := func() int {
+		files, _ := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+		return len(files)
+	}
+	assert.Equal(t, 3, count(), "ouch")
+
+	c.MaxAge = 50 * time.Hour
+	assert.Nil(t, c.Prune(now), "ouch")
+	assert.Equal(t, 2, count(), "too old")
+	assert.Nil(t, c.entry(*MustParseURL("http://example.com/c")), "gone")
+
+	c.MaxBytes = 1500
+	assert.Nil(t, c.Prune(now), "ouch")
+	assert.Equal(t, 1, count(), "too big")
+	assert.NotNil(t, c.entry(*MustParseURL("http://example.com/a")), "the most recent stays")
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Find symptoms of layout changes in the broadcasts of a station's day,
+// see CheckDay.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Bounds of plausible broadcast durations, see CheckDay.
+var (
+	MinDuration = time.Minute
+	MaxDuration = 12 * time.Hour
+)
+
+func checkLabel(bc Broadcast) string {
+	return fmt.Sprintf("%s '%s'", bc.Time.Format("2006-01-02 15:04"), bc.Title)
+}
+
+// Problems of the broadcasts touching [from,until): gaps, overlaps, empty
+// titles, missing or implausible ends. Broadcasts scraped twice count once.
+func CheckDay(bcs []Broadcast, from time.Time, until time.Time) (problems []string) {
+	seen := make(map[string]bool)
+	day := make([]Broadcast, 0, len(bcs))
+	for _, bc := range bcs {
+		if !bc.Time.Before(until) || (nil != bc.DtEnd && !bc.DtEnd.After(from)) {
+			continue
+		}
+		key := bc.Time.Format(time.RFC3339) + " " + bc.Title
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		day = append(day, bc)
+	}
+	if 0 == len(day) {
+		return []string{fmt.Sprintf("no broadcasts %s - %s", from.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"))}
+	}
+	sort.SliceStable(day, func(a, b int) bool { return day[a].Time.Before(day[b].Time) })
+
+	covered := from // end of the contiguous coverage so far
+	open := false   // the previous one has no (usable) end
+	for _, bc := range day {
+		if "" == bc.Title {
+			problems = append(problems, "no title "+checkLabel(bc))
+		}
+		if open {
+			open = false
+		} else if bc.Time.After(covered) {
+			problems = append(problems, fmt.Sprintf("gap %s - %s", covered.Format("2006-01-02 15:04"), bc.Time.Format("2006-01-02 15:04")))
+		} else if bc.Time.Before(covered) && bc.Time.After(from) {
+			problems = append(problems, fmt.Sprintf("overlap of %s %s", covered.Sub(bc.Time), checkLabel(bc)))
+		}
+		if bc.Time.After(covered) {
+			covered = bc.Time
+		}
+		if nil == bc.DtEnd {
+			problems = append(problems, "no end "+checkLabel(bc))
+			open = true
+			continue
+		}
+		switch dt := bc.DtEnd.Sub(bc.Time); {
+		case dt <= 0:
+			problems = append(problems, "ends before start "+checkLabel(bc))
+			open = true
+			continue
+		case dt < MinDuration || MaxDuration < dt:
+			problems = append(problems, fmt.Sprintf("duration %s %s", dt, checkLabel(bc)))
+		}
+		if bc.DtEnd.After(covered) {
+			covered = *bc.DtEnd
+		}
+	}
+	if covered.Before(until) {
+		problems = append(problems, fmt.Sprintf("gap %s - %s", covered.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04")))
+	}
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDayStart(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	st := Station{CloseDown: "05:00", TimeZone: berlin}
+	assert.Equal(t, "2016-08-25T05:00:00+02:00", st.DayStart(time.Date(2016, time.August, 25, 18, 0, 0, 0, berlin)).Format(time.RFC3339), "ouch")
+	assert.Equal(t, "2016-08-24T05:00:00+02:00", st.DayStart(time.Date(2016, time.August, 25, 4, 59, 0, 0, berlin)).Format(time.RFC3339), "still yesterday")
+	assert.Equal(t, "2016-03-01T05:00:00+01:00", st.DayStart(time.Date(2016, time.March, 2, 2, 0, 0, 0, time.UTC)).Format(time.RFC3339), "month and zone")
+	assert.Equal(t, "2016-03-27T05:00:00+02:00", st.DayStart(time.Date(2016, time.March, 27, 12, 0, 0, 0, berlin)).Format(time.RFC3339), "dst")
+}
+
+func TestCheckDay(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(d, h, m int) time.Time { return time.Date(2016, time.August, d, h, m, 0, 0, berlin) }
+	bc := func(title string, start time.Time, end time.Time) Broadcast {
+		ret := Broadcast{}
+		ret.Title, ret.Time, ret.DtEnd = title, start, &end
+		return ret
+	}
+	from, until := at(25, 5, 0), at(26, 5, 0)
+
+	ok := []Broadcast{
+		bc("Nacht", at(25, 0, 0), at(25, 5, 0)), // before, ignored
+		bc("Morgen", at(25, 5, 0), at(25, 12, 0)),
+		bc("Tag", at(25, 12, 0), at(25, 23, 0)),
+		bc("Morgen", at(25, 5, 0), at(25, 12, 0)), // scraped twice
+		bc("Nacht", at(25, 23, 0), at(26, 6, 0)),
+	}
+	assert.Equal(t, 0, len(CheckDay(ok, from, until)), "ouch")
+
+	noEnd := bc("Ohne Ende", at(25, 13, 0), at(25, 13, 0))
+	noEnd.DtEnd = nil
+	broken := []Broadcast{
+		bc("Morgen", at(25, 6, 0), at(25, 12, 0)),
+		bc("", at(25, 12, 0), at(25, 13, 0)),
+		bc("Zu früh", at(25, 12, 30), at(25, 13, 0)),
+		noEnd,
+		bc("Rückwärts", at(25, 15, 0), at(25, 14, 0)),
+		bc("Lang", at(25, 15, 0), at(26, 3, 30)),
+	}
+	assert.Equal(t, []string{
+		"gap 2016-08-25 05:00 - 2016-08-25 06:00",
+		"no title 2016-08-25 12:00 ''",
+		"overlap of 30m0s 2016-08-25 12:30 'Zu früh'",
+		"no end 2016-08-25 13:00 'Ohne Ende'",
+		"ends before start 2016-08-25 15:00 'Rückwärts'",
+		"duration 12h30m0s 2016-08-25 15:00 'Lang'",
+		"gap 2016-08-26 03:30 - 2016-08-26 05:00",
+	}, CheckDay(broken, from, until), "ouch")
+
+	assert.Equal(t, []string{"no broadcasts 2016-08-25 05:00 - 2016-08-26 05:00"}, CheckDay(ok[:1], from, until), "ouch")
+}
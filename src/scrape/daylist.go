@@ -0,0 +1,255 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// A scraper for program websites listing the broadcasts of a day on one page,
+// configured by data, see DayList.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+)
+
+// Where to find what on a day page, selectors as Select understands them.
+// Each Item needs a start time, the previous Item ends there.
+type DayList struct {
+	DayURL      string // appended to ProgramURL after day.Format, e.g. "?daterequest=2006-01-02"
+	Item        string // one per broadcast, the selectors below apply within
+	Time        string // its text is the start time
+	TimeFormat  string // of that text, e.g. "15:04"
+	Title       string
+	Link        string // optional, its href becomes the Subject
+	Description string // optional, without the Title if that's inside
+	Publisher   string // optional
+	Language    string // optional
+}
+
+// htdocs/stations/<id>/app/station.cfg like
+//
+//	{
+//		title = 'M 94.5',
+//		program_url = 'http://www.m945.de/programm/',
+//		scrape_source = 'daylist',
+//		daylist_url = '?daterequest=2006-01-02',
+//		daylist_item = 'div.item',
+//		daylist_time = 'div.time',
+//		daylist_time_format = '15:04',
+//		daylist_title = 'div.descr > a',
+//		daylist_link = 'div.descr > a',
+//		daylist_description = 'div.descr',
+//		daylist_publisher = 'http://www.m945.de/',
+//		daylist_language = 'de',
+//		day_start = '0000',
+//		timezone = 'Europe/Berlin',
+//	}
+func init() {
+	RegisterSource("daylist", func(st Station) Scraper { return DayListFromParams(st.Params).Scraper(st) })
+}
+
+// The daylist_* values of a station.cfg.
+func DayListFromParams(m map[string]string) *DayList {
+	return &DayList{
+		DayURL:      m["daylist_url"],
+		Item:        m["daylist_item"],
+		Time:        m["daylist_time"],
+		TimeFormat:  m["daylist_time_format"],
+		Title:       m["daylist_title"],
+		Link:        m["daylist_link"],
+		Description: m["daylist_description"],
+		Publisher:   m["daylist_publisher"],
+		Language:    m["daylist_language"],
+	}
+}
+
+// The page of the day containing t, starting at midnight in the station's
+// TimeZone.
+func (dl *DayList) Day(st Station, t time.Time) (ret TimeURL, err error) {
+	t = t.In(st.TimeZone)
+	u, err := url.Parse(st.ProgramURL.String() + t.Format(dl.DayURL))
+	if nil != err {
+		return
+	}
+	ret = TimeURL{
+		Time:    time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, st.TimeZone),
+		Source:  *u,
+		Station: st,
+	}
+	return
+}
+
+// The root Scraper of st, queueing the days due, see IncrementalNows.
+func (dl *DayList) Scraper(st Station) Scraper {
+	return &dayListStation{Station: st, def: dl}
+}
+
+// The Scraper of one day page, see Day.
+func (dl *DayList) DayScraper(day TimeURL) Scraper {
+	return &dayListDay{TimeURL: day, def: dl}
+}
+
+type dayListStation struct {
+	Station
+	def *DayList
+}
+
+func (s *dayListStation) Matches(nows []time.Time) (ok bool) {
+	return true
+}
+
+func (s *dayListStation) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	for _, t0 := range IncrementalNows(Now()) {
+		day, err := s.def.Day(s.Station, t0)
+		if nil != err {
+			return nil, nil, err
+		}
+		jobs = append(jobs, s.def.DayScraper(day))
+	}
+	return
+}
+
+type dayListDay struct {
+	TimeURL
+	def *DayList
+}
+
+func (d *dayListDay) Matches(nows []time.Time) (ok bool) {
+	return true
+}
+
+func (d *dayListDay) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	bo, cr0, err := HttpGetBody(ctx, d.Source)
+	if nil == bo {
+		return nil, nil, err
+	}
+	defer bo.Close()
+	bcs, err := d.def.ParseReader(d.TimeURL, bo, cr0)
+	for _, bc := range bcs {
+		results = append(results, bc)
+	}
+	return
+}
+
+// Parse the day page from read, see Parse.
+func (dl *DayList) ParseReader(day TimeURL, read io.Reader, cr0 *CountingReader) (ret []*Broadcast, err error) {
+	cr := NewCountingReader(read)
+	root, err := html.Parse(cr)
+	ReportLoad("🐦", cr0, cr, day.Source)
+	if nil != err {
+		return
+	}
+	return dl.Parse(day, root)
+}
+
+// The broadcasts of the day page root. Items without a Time node are skipped,
+// a time earlier than the one before is past midnight. The last one ends at
+// midnight, guessed, see Stitch.
+func (dl *DayList) Parse(day TimeURL, root *html.Node) (ret []*Broadcast, err error) {
+	ms := make(map[string]scrape.Matcher)
+	for _, sel := range []string{dl.Item, dl.Time, dl.Title, dl.Link, dl.Description} {
+		if "" == sel {
+			continue
+		}
+		if ms[sel], err = Select(sel); nil != err {
+			return
+		}
+	}
+	if nil == ms[dl.Item] || nil == ms[dl.Time] || nil == ms[dl.Title] {
+		return nil, errors.New("DayList: item, time and title selectors are mandatory")
+	}
+	var publisher, language *string
+	if "" != dl.Publisher {
+		publisher = &dl.Publisher
+	}
+	if "" != dl.Language {
+		language = &dl.Language
+	}
+	for _, item := range scrape.FindAll(root, ms[dl.Item]) {
+		tim, ok := scrape.Find(item, ms[dl.Time])
+		if !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(dl.TimeFormat, strings.TrimSpace(scrape.Text(tim)), day.TimeZone)
+		if nil != err {
+			return nil, NewParseError(day.Source, tim, err)
+		}
+		bc := &Broadcast{BroadcastURL: BroadcastURL{TimeURL: day}}
+		bc.Publisher = publisher
+		bc.Language = language
+		bc.Time = time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, day.TimeZone)
+		if 0 < len(ret) {
+			prev := ret[len(ret)-1]
+			for bc.Time.Before(prev.Time) {
+				bc.Time = bc.Time.AddDate(0, 0, 1)
+			}
+			prev.DtEnd = &bc.Time
+		}
+		title, ok := scrape.Find(item, ms[dl.Title])
+		if ok {
+			bc.Title = strings.TrimSpace(scrape.Text(title))
+		}
+		if "" != dl.Link {
+			if a, ok := scrape.Find(item, ms[dl.Link]); ok && "" != scrape.Attr(a, "href") {
+				u, err := url.Parse(scrape.Attr(a, "href"))
+				if nil != err {
+					return nil, NewParseError(day.Source, a, err)
+				}
+				bc.Subject = day.Source.ResolveReference(u)
+			}
+		}
+		if "" != dl.Description {
+			if desc, ok := scrape.Find(item, ms[dl.Description]); ok {
+				if nil != title && isAncestor(desc, title) {
+					title.Parent.RemoveChild(title)
+				}
+				description := TextWithBrFromNodeSet([]*html.Node{desc})
+				bc.Description = &description
+			}
+		}
+		ret = append(ret, bc)
+	}
+	if 0 < len(ret) {
+		last := ret[len(ret)-1]
+		end := time.Date(day.Year(), day.Month(), day.Day(), 24, 0, 0, 0, day.TimeZone)
+		for !end.After(last.Time) {
+			end = end.AddDate(0, 0, 1)
+		}
+		last.DtEnd = &end
+		last.DtEndGuessed = true // until the next day tells, see Stitch
+	}
+	return
+}
+
+func isAncestor(a, n *html.Node) bool {
+	for p := n.Parent; nil != p; p = p.Parent {
+		if a == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDayListParse(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	st := Station{Identifier: "dl", ProgramURL: MustParseURL("http://example.com/programm/"), TimeZone: tz}
+	dl := DayList{
+		DayURL:      "?tag=2006-01-02",
+		Item:        "li",
+		Time:        "span.zeit",
+		TimeFormat:  "15.04 Uhr",
+		Title:       "h3",
+		Link:        "h3 a",
+		Description: "div.text",
+		Language:    "de",
+	}
+	day, err := dl.Day(st, time.Date(2016, time.March, 26, 23, 30, 0, 0, time.UTC))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "http://example.com/programm/?tag=2016-03-27", day.Source.String(), "in the station's zone")
+	assert.Equal(t, "2016-03-27T00:00:00+01:00", day.Time.Format(time.RFC3339), "ouch")
+
+	bcs, err := dl.ParseReader(day, strings.NewReader(`<ul>
+<li><h2>Sonntag</h2></li>
+<li><span class="zeit">05.00 Uhr</span><h3><a href="/sendung/morgen">Morgen</a></h3><div class="text">Wach<br/>werden</div></li>
+<li><span class="zeit">12.00 Uhr</span><div class="text"><h3>Mittag</h3>Essen</div></li>
+<li><span class="zeit">23.00 Uhr</span><h3>Nacht</h3></li>
+<li><span class="zeit">01.00 Uhr</span><h3>Später</h3></li>
+</ul>`), nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 4, len(bcs), "no time, no broadcast")
+
+	assert.Equal(t, "Morgen", bcs[0].Title, "ouch")
+	assert.Equal(t, "2016-03-27T05:00:00+02:00", bcs[0].Time.Format(time.RFC3339), "after the DST switch")
+	assert.Equal(t, "2016-03-27T12:00:00+02:00", bcs[0].DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "http://example.com/sendung/morgen", bcs[0].Subject.String(), "ouch")
+	assert.Equal(t, "Wach\nwerden", *bcs[0].Description, "ouch")
+	assert.Equal(t, "de", *bcs[0].Language, "ouch")
+	assert.Nil(t, bcs[0].Publisher, "ouch")
+	assert.Equal(t, "http://example.com/programm/?tag=2016-03-27", bcs[0].Source.String(), "ouch")
+	assert.Equal(t, "dl", bcs[0].Station.Identifier, "ouch")
+	assert.False(t, bcs[0].DtEndGuessed, "ouch")
+
+	assert.Equal(t, "Mittag", bcs[1].Title, "ouch")
+	assert.Equal(t, "Essen", *bcs[1].Description, "without the title")
+	assert.Nil(t, bcs[1].Subject, "ouch")
+
+	assert.Nil(t, bcs[2].Description, "ouch")
+	assert.Equal(t, "2016-03-28T01:00:00+02:00", bcs[3].Time.Format(time.RFC3339), "past midnight")
+	assert.Equal(t, "2016-03-28T01:00:00+02:00", bcs[2].DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "2016-03-29T00:00:00+02:00", bcs[3].DtEnd.Format(time.RFC3339), "ouch")
+	assert.True(t, bcs[3].DtEndGuessed, "ouch")
+
+	_, err = dl.ParseReader(day, strings.NewReader(`<ul><li><span class="zeit">5 Uhr</span><h3>Kaputt</h3></li></ul>`), nil)
+	assert.Equal(t, "parse http://example.com/programm/?tag=2016-03-27 at <span class=\"zeit\">5 Uhr</span>: parsing time \"5 Uhr\" as \"15.04 Uhr\": cannot parse \" Uhr\" as \".\"", err.Error(), "ouch")
+
+	dl.Title = ""
+	_, err = dl.ParseReader(day, strings.NewReader(`<ul></ul>`), nil)
+	assert.NotNil(t, err, "title is mandatory")
+}
+
+func TestDayListSource(t *testing.T) {
+	defer func(n func() time.Time) { Now = n }(Now)
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	Now = func() time.Time { return time.Date(2015, time.November, 14, 12, 0, 0, 0, tz) }
+
+	factory, ok := source("daylist")
+	assert.True(t, ok, "registered by init")
+	st := Station{Identifier: "dl", ProgramURL: MustParseURL("http://www.m945.de/programm/"), TimeZone: tz, Params: map[string]string{
+		"daylist_url":  "?daterequest=2006-01-02",
+		"daylist_item": "div.item",
+	}}
+	jobs, _, err := factory(st).Scrape(context.Background())
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 5, len(jobs), "ouch")
+	day := jobs[1].(*dayListDay)
+	assert.Equal(t, "http://www.m945.de/programm/?daterequest=2015-11-15", day.Source.String(), "ouch")
+	assert.Equal(t, "div.item", day.def.Item, "ouch")
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Run a single parser level of a station, e.g. to repair a scraper after
+// the station changed its markup.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Create the Scraper for one level of a station, e.g. a day or a broadcast
+// page. parent stands in for what the level above would pass down.
+type LevelFactory func(parent TimeURL) Scraper
+
+// Make a level known for a registered station, see NewLevelScraper.
+func RegisterLevel(identifier string, name string, factory LevelFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	reg, ok := registry.m[identifier]
+	if !ok {
+		panic("register the station first: " + identifier)
+	}
+	if nil == reg.levels {
+		reg.levels = make(map[string]LevelFactory)
+	}
+	reg.levels[name] = factory
+	registry.m[identifier] = reg
+}
+
+// Names of the levels of a station, sorted. "station" is always there.
+func Levels(identifier string) (ret []string) {
+	registry.Lock()
+	defer registry.Unlock()
+	ret = []string{"station"}
+	for name := range registry.m[identifier].levels {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return
+}
+
+// The Scraper for the page at source as seen by the given level, "station"
+// being the root. day is the date the level above would pass along, the
+// station's CloseDown that day.
+func NewLevelScraper(identifier string, level string, source url.URL, day time.Time) (Scraper, error) {
+	registry.Lock()
+	reg, ok := registry.m[identifier]
+	registry.Unlock()
+	if !ok {
+		return nil, errors.New("unknown station: " + identifier)
+	}
+	st := reg.station
+	st.RegisterLimit()
+	if "station" == level {
+		// the root, e.g. a calendar linking the day pages
+		st.ProgramURL = &source
+		return reg.factory(st), nil
+	}
+	factory, ok := reg.levels[level]
+	if !ok {
+		return nil, errors.New("unknown level '" + level + "' for " + identifier + ", try one of: " + strings.Join(Levels(identifier), ", "))
+	}
+	if "" != st.CloseDown && nil != st.TimeZone {
+		// noon, so it's the day asked for
+		y, m, d := day.Date()
+		day = st.DayStart(time.Date(y, m, d, 12, 0, 0, 0, st.TimeZone))
+	}
+	return factory(TimeURL{Time: day, Source: source, Station: st}), nil
+}
+
+// The stand-in response body, read at most once.
+type fixedBody struct {
+	sync.Mutex
+	read io.Reader
+}
+
+func (b *fixedBody) take() (ret io.Reader) {
+	b.Lock()
+	defer b.Unlock()
+	ret, b.read = b.read, nil
+	return
+}
+
+type bodyKey struct{}
+
+// Have the next HttpGetBody or HttpPostForm return read instead of going
+// online, e.g. to run a level on a saved page.
+func WithBody(ctx context.Context, read io.Reader) context.Context {
+	return context.WithValue(ctx, bodyKey{}, &fixedBody{read: read})
+}
+
+func bodyFrom(ctx context.Context) (io.ReadCloser, *CountingReader) {
+	b, _ := ctx.Value(bodyKey{}).(*fixedBody)
+	if nil == b {
+		return nil, nil
+	}
+	read := b.take()
+	if nil == read {
+		return nil, nil
+	}
+	cr := NewCountingReader(read)
+	cr.stats = statsFrom(ctx)
+	return ioutil.NopCloser(cr), cr
+}
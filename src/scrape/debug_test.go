@@ -0,0 +1,88 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Fetches its Source and keeps the body as Title.
+type pageScraper BroadcastURL
+
+func (f *pageScraper) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	bo, _, err := HttpGetBody(ctx, f.Source)
+	if nil != err {
+		return
+	}
+	defer bo.Close()
+	data, err := ioutil.ReadAll(bo)
+	f.Title = string(data)
+	return
+}
+
+func (f *pageScraper) Matches(nows []time.Time) bool { return true }
+
+func TestLevels(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	Register(Station{Identifier: "test-level", CloseDown: "05:00", TimeZone: berlin, ProgramURL: MustParseURL("http://example.com/")}, func(st Station) Scraper { return nopScraper(st.ProgramURL.String()) })
+	defer func() {
+		registry.Lock()
+		delete(registry.m, "test-level")
+		registry.Unlock()
+	}()
+	RegisterLevel("test-level", "day", func(tu TimeURL) Scraper { f := pageScraper(BroadcastURL{TimeURL: tu}); return &f })
+	assert.Equal(t, []string{"day", "station"}, Levels("test-level"), "ouch")
+
+	s, err := NewLevelScraper("test-level", "station", *MustParseURL("http://example.com/other"), time.Now())
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, nopScraper("http://example.com/other"), s, "ouch")
+
+	day := time.Date(2016, time.August, 25, 0, 0, 0, 0, time.UTC)
+	s, err = NewLevelScraper("test-level", "day", *MustParseURL("http://example.invalid/day"), day)
+	assert.Nil(t, err, "ouch")
+	f := s.(*pageScraper)
+	assert.Equal(t, "2016-08-25T05:00:00+02:00", f.Time.Format(time.RFC3339), "the station's CloseDown")
+	assert.Equal(t, "test-level", f.Station.Identifier, "ouch")
+
+	// served from memory instead of example.invalid
+	ctx := WithBody(context.Background(), strings.NewReader("saved page"))
+	_, _, err = s.Scrape(ctx)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "saved page", f.Title, "ouch")
+	_, cr := bodyFrom(ctx)
+	assert.Nil(t, cr, "used up")
+
+	_, err = NewLevelScraper("test-level", "week", url.URL{}, day)
+	assert.Equal(t, "unknown level 'week' for test-level, try one of: day, station", err.Error(), "ouch")
+	_, err = NewLevelScraper("nope", "day", url.URL{}, day)
+	assert.Equal(t, "unknown station: nope", err.Error(), "ouch")
+}
@@ -20,6 +20,7 @@
 package dlf // import "purl.mro.name/recorder/radio/scrape/dlf"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -53,6 +54,13 @@ func Station(identifier string) *station {
 	return nil
 }
 
+func init() {
+	for _, id := range []string{"dlf", "drk"} {
+		r.Register(r.Station(*Station(id)), func(st r.Station) r.Scraper { s := station(st); return &s })
+		r.RegisterLevel(id, "day", func(tu r.TimeURL) r.Scraper { return timeURL(tu) })
+	}
+}
+
 /// Stringer
 func (s *station) String() string {
 	return fmt.Sprintf("Station '%s'", s.Name)
@@ -63,10 +71,13 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // Synthesise calItemRangeURLs for incremental scraping and queue them up
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	now := time.Now()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	now := r.Now()
 	for _, t0 := range r.IncrementalNows(now) {
-		day, _ := s.dayURLForDate(t0)
+		day, err := s.dayURLForDate(t0)
+		if nil != err {
+			return nil, nil, err
+		}
 		jobs = append(jobs, r.Scraper(*day))
 	}
 	return
@@ -76,9 +87,13 @@ func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error
 // http://www.deutschlandfunk.de/programmvorschau.281.de.html?drbm:date=19.11.2015
 
 func (s *station) dayURLForDate(day time.Time) (ret *timeURL, err error) {
+	u, err := url.Parse(s.ProgramURL.String() + day.Format("?drbm:date=02.01.2006"))
+	if nil != err {
+		return
+	}
 	r := timeURL(r.TimeURL{
 		Time:    time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.TimeZone),
-		Source:  *r.MustParseURL(s.ProgramURL.String() + day.Format("?drbm:date=02.01.2006")),
+		Source:  *u,
 		Station: r.Station(*s),
 	})
 	ret = &r
@@ -96,8 +111,8 @@ func (day timeURL) Matches(nows []time.Time) (ok bool) {
 }
 
 // Scrape broadcasts from a day page.
-func (day timeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := day.parseBroadcastsFromURL()
+func (day timeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := day.parseBroadcastsFromURL(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			results = append(results, bc)
@@ -141,9 +156,18 @@ func (day *timeURL) parseBroadcastsFromNode(root *html.Node) (ret []*r.Broadcast
 			if "" == aID {
 				continue
 			}
+			if len(aID) < 4 {
+				return nil, r.NewParseError(day.Source, at, errors.New("Couldn't parse hhmm"))
+			}
 			bc.Source.Fragment = aID
-			hour := r.MustParseInt(aID[0:2])
-			minute := r.MustParseInt(aID[2:4])
+			hour, err := r.ParseInt(day.Source, aID[0:2])
+			if nil != err {
+				return nil, err
+			}
+			minute, err := r.ParseInt(day.Source, aID[2:4])
+			if nil != err {
+				return nil, err
+			}
 			if 24 < hour || 60 < minute {
 				continue
 			}
@@ -201,6 +225,7 @@ func (day *timeURL) parseBroadcastsFromNode(root *html.Node) (ret []*r.Broadcast
 	if index > 0 {
 		midnight := time.Date(day.Year(), day.Month(), day.Day(), 24, 0, 0, 0, day.TimeZone)
 		ret[index-1].DtEnd = &midnight
+		ret[index-1].DtEndGuessed = true // until the next day tells, see r.Stitch
 	}
 	return
 }
@@ -215,10 +240,11 @@ func (day *timeURL) parseBroadcastsFromReader(read io.Reader, cr0 *r.CountingRea
 	return day.parseBroadcastsFromNode(root)
 }
 
-func (day *timeURL) parseBroadcastsFromURL() (ret []*r.Broadcast, err error) {
-	bo, cr, err := r.HttpGetBody(day.Source)
+func (day *timeURL) parseBroadcastsFromURL(ctx context.Context) (ret []*r.Broadcast, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, day.Source)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return day.parseBroadcastsFromReader(bo, cr)
 }
@@ -23,6 +23,7 @@
 package dlf // import "purl.mro.name/recorder/radio/scrape/dlf"
 
 import (
+	"bytes"
 	"os"
 	"testing"
 	"time"
@@ -44,6 +45,20 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", s.TimeZone.String(), "ouch: TimeZone")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("dlf"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 86, len(bcs), "today twice, for now and now + 12h")
+	first := bcs[0]
+	last := bcs[42]
+	assert.Equal(t, "dlf", first.Station.Identifier, "ouch")
+	assert.Equal(t, "Nachrichten", first.Title, "ouch")
+	assert.Equal(t, "2015-11-14T00:00:00+01:00", first.Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "National- und Europahymne", last.Title, "ouch")
+	assert.Equal(t, "2015-11-15T00:00:00+01:00", last.DtEnd.Format(time.RFC3339), "ouch")
+}
+
 func TestDayURLForDate(t *testing.T) {
 	s := Station("dlf")
 	u, err := s.dayURLForDate(time.Date(2015, 11, 30, 5, 0, 0, 0, s.TimeZone))
@@ -199,3 +214,45 @@ func TestParseBroadcasts_2(t *testing.T) {
 	assert.Nil(t, bc.Creator, "Creator")
 	assert.Nil(t, bc.Copyright, "Copyright")
 }
+
+func TestParseBroadcastsJSONRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/2015-10-25-dlf-programm.html")
+	assert.NotNil(t, f, "ouch")
+	assert.Nil(t, err, "ouch")
+
+	s := Station("dlf")
+	u := timeURL(r.TimeURL{
+		Time:    time.Date(2015, time.October, 25, 0, 0, 0, 0, s.TimeZone),
+		Source:  *r.MustParseURL("http://www.deutschlandfunk.de/programmvorschau.281.de.html?cal:month=10&drbm:date=25.10.2015"),
+		Station: r.Station(*s),
+	})
+
+	bcs, err := u.parseBroadcastsFromReader(f, nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 46, len(bcs), "ouch")
+
+	buf := new(bytes.Buffer)
+	for _, bc := range bcs {
+		assert.Nil(t, bc.WriteAsJSON(buf), "ouch")
+	}
+	res, err := r.ReadBroadcastsJSON(buf)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, len(bcs), len(res), "ouch")
+	for i, bc := range res {
+		bc0 := bcs[i]
+		assert.Equal(t, "dlf", bc.Station.Identifier, "ouch: Station.Identifier")
+		assert.Equal(t, bc0.Title, bc.Title, "ouch: Title")
+		assert.Equal(t, bc0.Source.String(), bc.Source.String(), "ouch: Source")
+		assert.Equal(t, bc0.Time.Format(time.RFC3339), bc.Time.Format(time.RFC3339), "ouch: Time")
+		assert.Equal(t, bc0.DtEnd.Format(time.RFC3339), bc.DtEnd.Format(time.RFC3339), "ouch: DtEnd")
+		if nil == bc0.Subject {
+			assert.Nil(t, bc.Subject, "ouch: Subject")
+		} else {
+			assert.Equal(t, bc0.Subject.String(), bc.Subject.String(), "ouch: Subject")
+		}
+		assert.Equal(t, *bc0.Description, *bc.Description, "ouch: Description")
+		assert.Equal(t, *bc0.Publisher, *bc.Publisher, "ouch: Publisher")
+		assert.Nil(t, bc.Modified, "ouch: Modified")
+		assert.Nil(t, bc.Image, "ouch: Image")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Typed errors of a scrape, so a bad page fails its job, not the run.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// A page didn't look as expected, most likely the layout changed.
+type ParseError struct {
+	URL  url.URL
+	Node string // excerpt of the offending markup or data, may be empty
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if "" == e.Node {
+		return fmt.Sprintf("parse %s: %s", e.URL.String(), e.Err)
+	}
+	return fmt.Sprintf("parse %s at %s: %s", e.URL.String(), e.Node, e.Err)
+}
+
+// A ParseError at node n (may be nil) of the page u.
+func NewParseError(u url.URL, n *html.Node, err error) *ParseError {
+	return &ParseError{URL: u, Node: excerpt(n), Err: err}
+}
+
+// Fetching URL failed without a response, see StatusError for non-2xx ones.
+type FetchError struct {
+	Method string
+	URL    url.URL
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.URL.String(), e.Err)
+}
+
+// A broadcast is not fit for output, e.g. ends before it starts.
+type ValidationError struct {
+	Source url.URL
+	Field  string
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %s: %s", e.Field, e.Source.String(), e.Msg)
+}
+
+// Fit for output? See ValidationError.
+func (b Broadcast) Validate() error {
+	if "" == b.Station.Identifier {
+		return &ValidationError{Source: b.Source, Field: "station", Msg: "no identifier"}
+	}
+	if nil != b.DtEnd && b.DtEnd.Before(b.Time) {
+		return &ValidationError{Source: b.Source, Field: "DtEnd", Msg: "dt < 0"}
+	}
+	return nil
+}
+
+// Replaces MustParseInt for page content. Base 10, up to 12 bit like MustParseInt.
+func ParseInt(u url.URL, s string) (int, error) {
+	ret, err := strconv.ParseInt(s, 10, 12)
+	if nil != err {
+		return 0, &ParseError{URL: u, Node: strconv.Quote(s), Err: err}
+	}
+	return int(ret), nil
+}
+
+// The start of the markup of n, for error messages.
+func excerpt(n *html.Node) string {
+	if nil == n {
+		return ""
+	}
+	var buf bytes.Buffer
+	if html.TextNode == n.Type {
+		buf.WriteString(n.Data)
+	} else {
+		html.Render(&buf, n)
+	}
+	const max = 80
+	ret := []rune(strings.Join(strings.Fields(buf.String()), " "))
+	if len(ret) > max {
+		return string(ret[:max]) + "..."
+	}
+	return string(ret)
+}
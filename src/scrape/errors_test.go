@@ -0,0 +1,99 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestParseError(t *testing.T) {
+	u := *MustParseURL("http://www.br.de/radio/bayern2/programmkalender/")
+	root, _ := html.Parse(strings.NewReader(`<dl><dt><a href="/x.html">  kaputt  </a></dt></dl>`))
+	a := root.FirstChild.LastChild.FirstChild.FirstChild.FirstChild
+	err := NewParseError(u, a, errors.New("Couldn't parse <a>"))
+	assert.Equal(t, `parse http://www.br.de/radio/bayern2/programmkalender/ at <a href="/x.html"> kaputt </a>: Couldn't parse <a>`, err.Error(), "ouch")
+	assert.False(t, isHttpError(err), "ouch")
+	assert.False(t, IsTransient(err), "ouch")
+
+	long := &html.Node{Type: html.TextNode, Data: strings.Repeat("ä", 100)}
+	assert.Equal(t, strings.Repeat("ä", 80)+"...", excerpt(long), "ouch")
+	assert.Equal(t, "parse http://www.br.de/radio/bayern2/programmkalender/: EOF", NewParseError(u, nil, errors.New("EOF")).Error(), "ouch")
+
+	_, e := ParseInt(u, "1x")
+	assert.Equal(t, `parse http://www.br.de/radio/bayern2/programmkalender/ at "1x": strconv.ParseInt: parsing "1x": invalid syntax`, e.Error(), "ouch")
+	i, e := ParseInt(u, "07")
+	assert.Nil(t, e, "ouch")
+	assert.Equal(t, 7, i, "ouch")
+}
+
+func TestFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u := *MustParseURL(srv.URL)
+	srv.Close()
+	_, _, err := HttpGetBody(context.Background(), u)
+	e, ok := err.(*FetchError)
+	assert.True(t, ok, "ouch")
+	assert.Equal(t, "GET", e.Method, "ouch")
+	assert.Equal(t, u, e.URL, "ouch")
+	assert.True(t, strings.HasPrefix(err.Error(), "GET "+srv.URL+": "), err.Error())
+	assert.True(t, isHttpError(err), "ouch")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = HttpGetBody(ctx, u)
+	assert.Equal(t, context.Canceled, err, "not the request's fault")
+}
+
+func TestValidate(t *testing.T) {
+	bc := Broadcast{}
+	bc.Source = *MustParseURL("http://www.br.de/radio/bayern2/sendungen/x.html")
+	bc.Time = time.Date(2016, time.August, 25, 18, 5, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := bc.WriteAsLuaTable(&buf)
+	assert.Equal(t, "invalid station http://www.br.de/radio/bayern2/sendungen/x.html: no identifier", err.Error(), "ouch")
+	assert.Equal(t, 0, buf.Len(), "ouch")
+
+	bc.Station.Identifier = "b2"
+	end := bc.Time.Add(-time.Minute)
+	bc.DtEnd = &end
+	err = bc.WriteAsLuaTable(&buf)
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok, "ouch")
+	assert.Equal(t, "invalid DtEnd http://www.br.de/radio/bayern2/sendungen/x.html: dt < 0", err.Error(), "ouch")
+
+	end = bc.Time.Add(time.Hour)
+	assert.Nil(t, bc.Validate(), "ouch")
+	assert.Nil(t, bc.WriteAsLuaTable(&buf), "ouch")
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Record http responses into a directory and replay them offline, see
+// Fixtures.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The file listing the recorded requests, one per line, tab separated:
+//
+//	method url request-body status content-type file
+//
+// The request body is '-' if empty, file is relative to the directory and
+// may be shared by several requests, even point to a testdata/ file.
+const FixtureIndex = "index.txt"
+
+// A http.RoundTripper serving responses from files in Dir, e.g. as
+// Client.Transport. With Record set it passes requests on to Transport
+// and writes the responses to Dir instead.
+type Fixtures struct {
+	Dir       string
+	Record    bool
+	Transport http.RoundTripper // for Record, nil: http.DefaultTransport
+
+	mu       sync.Mutex
+	index    map[string]fixture
+	recorded time.Time
+	started  bool // recording
+}
+
+type fixture struct {
+	Status      int
+	ContentType string
+	File        string
+}
+
+func fixtureKey(method string, url string, body string) string {
+	if "" == body {
+		body = "-"
+	}
+	return method + "\t" + url + "\t" + body
+}
+
+const recordedPrefix = "# recorded "
+
+// When the recording started, e.g. for Now when replaying. Zero if unknown.
+func (f *Fixtures) Recorded() (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.load()
+	return f.recorded, err
+}
+
+// must hold f.mu
+func (f *Fixtures) load() error {
+	if nil != f.index {
+		return nil
+	}
+	f.index = make(map[string]fixture)
+	file, err := os.Open(filepath.Join(f.Dir, FixtureIndex))
+	if nil != err {
+		if f.Record && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for no := 1; scanner.Scan(); no++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, recordedPrefix) {
+			if f.recorded, err = time.Parse(time.RFC3339, strings.TrimPrefix(line, recordedPrefix)); nil != err {
+				return fmt.Errorf("%s:%d: %s", file.Name(), no, err)
+			}
+			continue
+		}
+		if "" == strings.TrimSpace(line) || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if 6 != len(cols) {
+			return fmt.Errorf("%s:%d: want 6 tab separated columns, got %d", file.Name(), no, len(cols))
+		}
+		status, err := strconv.Atoi(cols[3])
+		if nil != err {
+			return fmt.Errorf("%s:%d: %s", file.Name(), no, err)
+		}
+		f.index[fixtureKey(cols[0], cols[1], cols[2])] = fixture{Status: status, ContentType: cols[4], File: cols[5]}
+	}
+	return scanner.Err()
+}
+
+// must hold f.mu
+func (f *Fixtures) save() error {
+	lines := make([]string, 0, len(f.index))
+	for key, fi := range f.index {
+		lines = append(lines, fmt.Sprintf("%s\t%d\t%s\t%s", key, fi.Status, fi.ContentType, fi.File))
+	}
+	sort.Strings(lines)
+	data := "# method\turl\trequest-body\tstatus\tcontent-type\tfile\n" + recordedPrefix + f.recorded.Format(time.RFC3339) + "\n" + strings.Join(lines, "\n") + "\n"
+	return ioutil.WriteFile(filepath.Join(f.Dir, FixtureIndex), []byte(data), 0644)
+}
+
+func (f *Fixtures) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if nil != req.Body {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); nil != err {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	key := fixtureKey(req.Method, req.URL.String(), string(body))
+	if f.Record {
+		return f.record(req, key, body)
+	}
+
+	f.mu.Lock()
+	err := f.load()
+	fi, ok := f.index[key]
+	f.mu.Unlock()
+	if nil != err {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New(strings.TrimSpace("no fixture for " + req.Method + " " + req.URL.String() + " " + string(body)))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(f.Dir, filepath.FromSlash(fi.File)))
+	if nil != err {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fi.Status, http.StatusText(fi.Status)),
+		StatusCode:    fi.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{fi.ContentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+var rxFixtureName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (f *Fixtures) record(req *http.Request, key string, body []byte) (*http.Response, error) {
+	tr := f.Transport
+	if nil == tr {
+		tr = http.DefaultTransport
+	}
+	out := req.WithContext(req.Context())
+	out.Header = http.Header{}
+	for k, v := range req.Header {
+		out.Header[k] = v
+	}
+	// have the transport decompress, so the files stay readable
+	out.Header.Del("Accept-Encoding")
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := tr.RoundTrip(out)
+	if nil != err {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if nil != err {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err = f.load(); nil != err {
+		return nil, err
+	}
+	if !f.started {
+		f.recorded, f.started = Now(), true
+	}
+	fi, ok := f.index[key]
+	if !ok {
+		name := rxFixtureName.ReplaceAllString(path.Base(req.URL.Path), "_")
+		if "" == strings.Trim(name, "._") {
+			name = "index"
+		}
+		fi.File = fmt.Sprintf("%03d-%s", len(f.index)+1, name)
+	}
+	fi.Status, fi.ContentType = resp.StatusCode, resp.Header.Get("Content-Type")
+	if err = os.MkdirAll(f.Dir, 0755); nil != err {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(f.Dir, fi.File), data, 0644); nil != err {
+		return nil, err
+	}
+	f.index[key] = fi
+	if err = f.save(); nil != err {
+		return nil, err
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	return resp, nil
+}
+
+// Run s and, depth first, the jobs it yields that match nows, one at a
+// time. Stops at the first error. Handy for end-to-end tests on Fixtures.
+func Crawl(ctx context.Context, s Scraper, nows []time.Time) (results []Broadcaster, err error) {
+	jobs, results, err := s.Scrape(ctx)
+	if nil != err {
+		return
+	}
+	for _, job := range jobs {
+		if !job.Matches(nows) {
+			continue
+		}
+		res, err := Crawl(ctx, job, nows)
+		results = append(results, res...)
+		if nil != err {
+			return results, err
+		}
+	}
+	return
+}
+
+// Crawl s like a station test does: replay the Fixtures in dir at the time
+// they were recorded. Client and Now are swapped meanwhile. Record dir
+// with 'scrape -record dir'.
+func CrawlFixtures(dir string, s Scraper) (ret []Broadcast, err error) {
+	fx := &Fixtures{Dir: dir}
+	now, err := fx.Recorded()
+	if nil != err {
+		return
+	}
+	defer func(c *http.Client, n func() time.Time) { Client, Now = c, n }(Client, Now)
+	Client = &http.Client{Transport: fx}
+	Now = func() time.Time { return now }
+
+	res, err := Crawl(context.Background(), s, IncrementalNows(now))
+	for _, b := range res {
+		if bc, ok := AsBroadcast(b); ok {
+			ret = append(ret, bc)
+		}
+	}
+	return
+}
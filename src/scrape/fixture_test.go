@@ -0,0 +1,172 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixturesRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-fixtures")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "POST" == r.Method {
+			r.ParseForm()
+			w.Write([]byte("posted " + r.PostForm.Get("day")))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("<html>" + r.URL.Path + "</html>"))
+		gz.Close()
+	}))
+
+	get := func(u string) string {
+		bo, _, err := HttpGetBody(context.Background(), *MustParseURL(u))
+		if nil != err {
+			return err.Error()
+		}
+		defer bo.Close()
+		data, _ := ioutil.ReadAll(bo)
+		return string(data)
+	}
+	post := func(day string) string {
+		bo, _, err := HttpPostForm(context.Background(), *MustParseURL(srv.URL+"/form"), url.Values{"day": {day}})
+		if nil != err {
+			return err.Error()
+		}
+		defer bo.Close()
+		data, _ := ioutil.ReadAll(bo)
+		return string(data)
+	}
+
+	defer func(c *http.Client, now func() time.Time) { Client, Now = c, now }(Client, Now)
+	recorded := time.Date(2016, time.August, 25, 18, 5, 0, 0, time.UTC)
+	Now = func() time.Time { return recorded }
+
+	Client = &http.Client{Transport: &Fixtures{Dir: dir, Record: true}}
+	assert.Equal(t, "<html>/day/1</html>", get(srv.URL+"/day/1"), "ouch")
+	assert.Equal(t, "<html>/</html>", get(srv.URL+"/"), "ouch")
+	assert.Equal(t, "posted 2016-08-25", post("2016-08-25"), "ouch")
+	srv.Close()
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "001-1"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "<html>/day/1</html>", string(data), "stored uncompressed")
+	data, err = ioutil.ReadFile(filepath.Join(dir, FixtureIndex))
+	assert.Nil(t, err, "ouch")
+	assert.True(t, strings.Contains(string(data), "\nPOST\t"+srv.URL+"/form\tday=2016-08-25\t200\t"), string(data))
+
+	fx := &Fixtures{Dir: dir}
+	tr, err := fx.Recorded()
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, recorded, tr, "ouch")
+
+	// the server is gone, so it's all from dir
+	Client = &http.Client{Transport: fx}
+	assert.Equal(t, "<html>/day/1</html>", get(srv.URL+"/day/1"), "ouch")
+	assert.Equal(t, "<html>/</html>", get(srv.URL+"/"), "ouch")
+	assert.Equal(t, "posted 2016-08-25", post("2016-08-25"), "ouch")
+	assert.True(t, strings.HasSuffix(get(srv.URL+"/day/2"), "no fixture for GET "+srv.URL+"/day/2"), "ouch")
+	assert.True(t, strings.HasSuffix(post("2016-08-26"), "no fixture for POST "+srv.URL+"/form day=2016-08-26"), "ouch")
+}
+
+func TestCrawl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-fixtures")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("<html/>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, FixtureIndex), []byte("# a comment\n"+
+		"GET\thttp://example.com/a\t-\t200\ttext/html\tpage.html\n"+
+		"GET\thttp://example.com/b\t-\t404\ttext/html\tpage.html\n"), 0644)
+
+	defer func(c *http.Client) { Client = c }(Client)
+	Client = &http.Client{Transport: &Fixtures{Dir: dir}}
+
+	s := tree{"http://example.com/a", []tree{{"http://example.com/a", nil}, {"http://example.com/skip", nil}}}
+	res, err := Crawl(context.Background(), s, nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(res), "the one not matching is skipped")
+
+	s = tree{"http://example.com/a", []tree{{"http://example.com/b", nil}, {"http://example.com/a", nil}}}
+	res, err = Crawl(context.Background(), s, nil)
+	assert.Equal(t, "404 Not Found http://example.com/b", err.Error(), "ouch")
+	assert.Equal(t, 1, len(res), "stops at the first error")
+}
+
+func TestCrawlFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrape-fixtures")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("<html/>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, FixtureIndex), []byte("# recorded 2016-08-25T18:05:00Z\n"+
+		"GET\thttp://example.com/a\t-\t200\ttext/html\tpage.html\n"), 0644)
+
+	c := Client
+	bcs, err := CrawlFixtures(dir, tree{"http://example.com/a", []tree{{"http://example.com/a", nil}}})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(bcs), "ouch")
+	assert.Equal(t, c, Client, "restored")
+	assert.NotEqual(t, 2016, Now().Year(), "restored")
+
+	_, err = CrawlFixtures(filepath.Join(dir, "missing"), tree{"http://example.com/a", nil})
+	assert.NotNil(t, err, "no index")
+}
+
+// Fetches url, yields a broadcast and its children as jobs.
+type tree struct {
+	url      string
+	children []tree
+}
+
+func (tr tree) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	bo, _, err := HttpGetBody(ctx, *MustParseURL(tr.url))
+	if nil != err {
+		return
+	}
+	bo.Close()
+	for _, c := range tr.children {
+		jobs = append(jobs, c)
+	}
+	results = append(results, Broadcast{})
+	return
+}
+
+func (tr tree) Matches(nows []time.Time) bool { return !strings.HasSuffix(tr.url, "/skip") }
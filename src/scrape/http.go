@@ -27,12 +27,16 @@
 package scrape
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
 func contains(haystack []string, needle string) bool {
@@ -44,35 +48,184 @@ func contains(haystack []string, needle string) bool {
 	return false
 }
 
+// Deadline for each single request including reading the body.
+var RequestTimeout = 30 * time.Second
+
+// Shared by all requests, deadlines come with the context.
+var Client = &http.Client{}
+
+// Called after each request, e.g. for metrics. status is 0 on transport errors.
+var OnResponse func(host string, status int)
+
+// Sent with every request unless empty.
+var UserAgent = "purl.mro.name/recorder (+http://purl.mro.name/recorder)"
+
+// A response body that releases the request context and host slot when closed.
+type body struct {
+	io.Reader
+	resp *http.Response
+	done func()
+	tee  *cacheTee // nil: not cached
+}
+
+func (b *body) Close() error {
+	defer b.done()
+	if nil != b.tee {
+		b.tee.drain()
+	}
+	return b.resp.Body.Close()
+}
+
+// Wait for the host's limits, then send req with a RequestTimeout deadline.
+// Call done when finished with the response.
+func do(ctx context.Context, req *http.Request) (*http.Response, func(), error) {
+	release, err := hostFor(req.URL.Host).acquire(ctx)
+	if nil != err {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	done := func() {
+		cancel()
+		release()
+	}
+	if "" != UserAgent {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+	resp, err := Client.Do(req.WithContext(ctx))
+	if nil != OnResponse {
+		status := 0
+		if nil == err {
+			status = resp.StatusCode
+		}
+		OnResponse(req.URL.Host, status)
+	}
+	if nil != err {
+		done()
+		return nil, nil, err
+	}
+	return resp, done, nil
+}
+
+// A non-2xx response.
+type StatusError struct {
+	StatusCode int
+	Status     string // e.g. "404 Not Found"
+	URL        url.URL
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s", e.Status, e.URL.String())
+}
+
+func statusError(resp *http.Response, u url.URL) error {
+	return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: u}
+}
+
+// Cancellation and the run deadline aren't the fault of the request, pass them as they are.
+func fetchError(ctx context.Context, method string, u url.URL, err error) error {
+	if e := ctx.Err(); nil != e {
+		return e
+	}
+	return &FetchError{Method: method, URL: u, Err: err}
+}
+
 /// One to fetch them all (except dlf with it's POST requests).
-func HttpGetBody(url url.URL) (io.Reader, *CountingReader, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", url.String(), nil)
-	req.Header.Set("Accept-Encoding", "gzip, deflate")
-	resp, err := client.Do(req)
-	if nil == resp {
+///
+/// A body from WithBody comes first. Uses Cache if set. Then a 304 is
+/// served from disk and the returned CountingReader is marked Cached.
+/// Non-2xx responses are errors. The caller has to Close the body.
+func HttpGetBody(ctx context.Context, url url.URL) (io.ReadCloser, *CountingReader, error) {
+	if bo, cr := bodyFrom(ctx); nil != bo {
+		return bo, cr, nil
+	}
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if nil != err {
 		return nil, nil, err
 	}
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	var entry *cacheEntry
+	if nil != Cache {
+		entry = Cache.prepare(req, url)
+	}
+	resp, done, err := do(ctx, req)
+	if nil != err {
+		return nil, nil, fetchError(ctx, req.Method, url, err)
+	}
+	ret := &body{resp: resp, done: done}
 	encs := resp.Header["Content-Encoding"]
+	var cr *CountingReader
+	switch {
+	case nil != entry && http.StatusNotModified == resp.StatusCode:
+		data, err := Cache.body(url)
+		if nil != err {
+			ret.Close()
+			return nil, nil, err
+		}
+		cr = NewCountingReader(bytes.NewReader(data))
+		cr.Cached = true
+		encs = entry.ContentEncoding
+	case resp.StatusCode < 200 || 300 <= resp.StatusCode:
+		ret.Close()
+		return nil, nil, statusError(resp, url)
+	case nil != Cache && http.StatusOK == resp.StatusCode:
+		ret.tee = &cacheTee{reader: resp.Body, commit: func(data []byte) {
+			if err := Cache.store(url, resp.Header, data); nil != err {
+				fmt.Fprintf(os.Stderr, "cache %s\n", err)
+			}
+		}}
+		cr = NewCountingReader(ret.tee)
+	default:
+		cr = NewCountingReader(resp.Body)
+	}
+	cr.stats = statsFrom(ctx)
+	ret.Reader = cr
 	switch {
 	case contains(encs, "gzip"), contains(encs, "deflate"):
-		cr := NewCountingReader(resp.Body)
-		ret, err := gzip.NewReader(cr)
-		return ret, cr, err
+		gz, err := gzip.NewReader(cr)
+		if nil != err {
+			ret.Close()
+			return nil, nil, err
+		}
+		ret.Reader = gz
 	case 0 == len(encs):
 		// NOP
 	default:
 		fmt.Fprintf(os.Stderr, "Strange compression: %s\n", encs)
 	}
+	return ret, cr, nil
+}
+
+/// POST a form, e.g. for radiofabrik. Same as HttpGetBody but without cache.
+func HttpPostForm(ctx context.Context, url url.URL, data url.Values) (io.ReadCloser, *CountingReader, error) {
+	if bo, cr := bodyFrom(ctx); nil != bo {
+		return bo, cr, nil
+	}
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader(data.Encode()))
+	if nil != err {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, done, err := do(ctx, req)
+	if nil != err {
+		return nil, nil, fetchError(ctx, req.Method, url, err)
+	}
+	ret := &body{resp: resp, done: done}
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		ret.Close()
+		return nil, nil, statusError(resp, url)
+	}
 	cr := NewCountingReader(resp.Body)
-	return cr, cr, err
+	cr.stats = statsFrom(ctx)
+	ret.Reader = cr
+	return ret, cr, nil
 }
 
 /// Sadly doesn't make things really simpler
-func GenericParseBroadcastFromURL(url url.URL, callback func(io.Reader, *CountingReader) ([]Broadcast, error)) (bc []Broadcast, err error) {
-	bo, cr, err := HttpGetBody(url)
+func GenericParseBroadcastFromURL(ctx context.Context, url url.URL, callback func(io.Reader, *CountingReader) ([]Broadcast, error)) (bc []Broadcast, err error) {
+	bo, cr, err := HttpGetBody(ctx, url)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return callback(bo, cr)
 }
@@ -0,0 +1,89 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpGetBodyStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "/gone" == r.URL.Path {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Write([]byte("<html/>"))
+	}))
+	defer srv.Close()
+
+	bo, cr, err := HttpGetBody(context.Background(), *MustParseURL(srv.URL + "/gone"))
+	assert.Nil(t, bo, "ouch")
+	assert.Nil(t, cr, "ouch")
+	assert.Equal(t, "410 Gone "+srv.URL+"/gone", err.Error(), "ouch")
+
+	bo, cr, err = HttpGetBody(context.Background(), *MustParseURL(srv.URL + "/ok"))
+	assert.Nil(t, err, "ouch")
+	data, _ := ioutil.ReadAll(bo)
+	assert.Nil(t, bo.Close(), "ouch")
+	assert.Equal(t, "<html/>", string(data), "ouch")
+	assert.Equal(t, int64(7), cr.TotalBytes, "ouch")
+}
+
+func TestHttpGetBodyDeadline(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hang:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	old := RequestTimeout
+	RequestTimeout = 50 * time.Millisecond
+	defer func() { RequestTimeout = old }()
+
+	t0 := time.Now()
+	_, _, err := HttpGetBody(context.Background(), *MustParseURL(srv.URL))
+	assert.NotNil(t, err, "per request deadline")
+	assert.True(t, time.Since(t0) < time.Second, "ouch")
+
+	RequestTimeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	t0 = time.Now()
+	_, _, err = HttpPostForm(ctx, *MustParseURL(srv.URL), nil)
+	assert.True(t, strings.Contains(err.Error(), "deadline exceeded"), err.Error())
+	assert.True(t, time.Since(t0) < time.Second, "run deadline")
+}
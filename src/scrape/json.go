@@ -0,0 +1,179 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// JSON (Lines) serialisation of broadcasts.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Flat JSON representation of a Broadcast. Keys follow the lua tables
+// (see WriteAsLuaTable) so downstream tooling can use the same names.
+type broadcastJSON struct {
+	Station      string  `json:"station"`
+	Title        string  `json:"title"`
+	Scheme       string  `json:"DC_scheme"`
+	Language     *string `json:"DC_language,omitempty"`
+	TitleSeries  *string `json:"DC_title_series,omitempty"`
+	TitleEpisode *string `json:"DC_title_episode,omitempty"`
+	Subject      string  `json:"DC_subject,omitempty"`
+	TimeStart    string  `json:"DC_format_timestart"`
+	TimeEnd      string  `json:"DC_format_timeend,omitempty"`
+	Duration     *int64  `json:"DC_format_duration,omitempty"`
+	Image        string  `json:"DC_image,omitempty"`
+	Description  *string `json:"DC_description,omitempty"`
+	Author       *string `json:"DC_author,omitempty"`
+	Publisher    *string `json:"DC_publisher,omitempty"`
+	Creator      *string `json:"DC_creator,omitempty"`
+	Copyright    *string `json:"DC_copyright,omitempty"`
+	Source       string  `json:"DC_source"`
+	Modified     string  `json:"DC_modified,omitempty"`
+}
+
+// Write one JSON object terminated by a newline (JSON Lines).
+func (b Broadcast) WriteAsJSON(w io.Writer) (err error) {
+	return json.NewEncoder(w).Encode(b)
+}
+
+// Implement json.Marshaler, otherwise the embedded time.Time would take over.
+func (b Broadcast) MarshalJSON() ([]byte, error) {
+	if "" == b.Station.Identifier {
+		return nil, errors.New("How can the identifier miss?")
+	}
+	fu := func(u *url.URL) string {
+		if nil == u {
+			return ""
+		}
+		return u.String()
+	}
+	ft := func(t *time.Time) string {
+		if nil == t {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	j := broadcastJSON{
+		Station:      b.Station.Identifier,
+		Title:        b.Title,
+		Scheme:       "/app/pbmi2003-recmod2012/",
+		Language:     b.Language,
+		TitleSeries:  b.TitleSeries,
+		TitleEpisode: b.TitleEpisode,
+		Subject:      fu(b.Subject),
+		TimeStart:    b.Time.Format(time.RFC3339),
+		TimeEnd:      ft(b.DtEnd),
+		Image:        fu(b.Image),
+		Description:  b.Description,
+		Author:       b.Author,
+		Publisher:    b.Publisher,
+		Creator:      b.Creator,
+		Copyright:    b.Copyright,
+		Source:       b.Source.String(),
+		Modified:     ft(b.Modified),
+	}
+	if nil != b.DtEnd {
+		dt := int64(b.DtEnd.Sub(b.Time) / time.Second)
+		if dt < 0 {
+			return nil, errors.New("dt < 0 for " + b.Source.String())
+		}
+		j.Duration = &dt
+	}
+	return json.Marshal(j)
+}
+
+// Implement json.Unmarshaler. Of the Station only the Identifier is restored.
+func (b *Broadcast) UnmarshalJSON(data []byte) (err error) {
+	var j broadcastJSON
+	if err = json.Unmarshal(data, &j); nil != err {
+		return
+	}
+	if "" == j.Station {
+		return errors.New("missing key 'station'")
+	}
+	pu := func(s string) (*url.URL, error) {
+		if "" == s {
+			return nil, nil
+		}
+		return url.Parse(s)
+	}
+	pt := func(s string) (*time.Time, error) {
+		if "" == s {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		return &t, err
+	}
+	ret := Broadcast{
+		TitleSeries:  j.TitleSeries,
+		TitleEpisode: j.TitleEpisode,
+		Description:  j.Description,
+		Author:       j.Author,
+		Language:     j.Language,
+		Publisher:    j.Publisher,
+		Creator:      j.Creator,
+		Copyright:    j.Copyright,
+	}
+	ret.Station.Identifier = j.Station
+	ret.Title = j.Title
+	if ret.Time, err = time.Parse(time.RFC3339, j.TimeStart); nil != err {
+		return
+	}
+	if ret.DtEnd, err = pt(j.TimeEnd); nil != err {
+		return
+	}
+	if ret.Modified, err = pt(j.Modified); nil != err {
+		return
+	}
+	if ret.Subject, err = pu(j.Subject); nil != err {
+		return
+	}
+	if ret.Image, err = pu(j.Image); nil != err {
+		return
+	}
+	src, err := url.Parse(j.Source)
+	if nil != err {
+		return
+	}
+	ret.Source = *src
+	*b = ret
+	return
+}
+
+// Read all broadcasts from a JSON Lines stream as written by WriteAsJSON.
+func ReadBroadcastsJSON(r io.Reader) (ret []Broadcast, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var b Broadcast
+		if err = dec.Decode(&b); nil != err {
+			if io.EOF == err {
+				err = nil
+			}
+			return
+		}
+		ret = append(ret, b)
+	}
+}
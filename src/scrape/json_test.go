@@ -0,0 +1,122 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAsJSON(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	de := "de"
+	episode := "anspruchsvoll - entspannt - weltoffen"
+	description := "Mit Riegler Hias feat. D'Hundskrippln\nModeration: Thomas Mehringer"
+	author := "Bayerischer Rundfunk"
+	dtEnd := time.Date(2016, time.August, 25, 18, 30, 0, 0, tz)
+	modified := time.Date(2016, time.August, 26, 0, 6, 13, 0, tz)
+	bc := Broadcast{
+		BroadcastURL: BroadcastURL{
+			TimeURL: TimeURL{
+				Time:    time.Date(2016, time.August, 25, 18, 5, 0, 0, tz),
+				Source:  *MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-772436.html"),
+				Station: Station{Identifier: "b2", Name: "Bayern 2", TimeZone: tz},
+			},
+			Title: "Bayern 2-radioMusik",
+		},
+		TitleEpisode: &episode,
+		DtEnd:        &dtEnd,
+		Modified:     &modified,
+		Subject:      MustParseURL("http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/index.html"),
+		Image:        MustParseURL("http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/rebekka-bakken-102.jpg?version=64958"),
+		Description:  &description,
+		Author:       &author,
+		Language:     &de,
+	}
+
+	buf := new(bytes.Buffer)
+	err := bc.WriteAsJSON(buf)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, strings.HasSuffix(buf.String(), "}\n"), "one line per broadcast")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"), "one line per broadcast")
+
+	var m map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &m)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "b2", m["station"], "ouch: station")
+	assert.Equal(t, "Bayern 2-radioMusik", m["title"], "ouch: title")
+	assert.Equal(t, "/app/pbmi2003-recmod2012/", m["DC_scheme"], "ouch: DC_scheme")
+	assert.Equal(t, "2016-08-25T18:05:00+02:00", m["DC_format_timestart"], "ouch: DC_format_timestart")
+	assert.Equal(t, "2016-08-25T18:30:00+02:00", m["DC_format_timeend"], "ouch: DC_format_timeend")
+	assert.Equal(t, float64(1500), m["DC_format_duration"], "ouch: DC_format_duration")
+	assert.Equal(t, "2016-08-26T00:06:13+02:00", m["DC_modified"], "ouch: DC_modified")
+	assert.Nil(t, m["DC_title_series"], "ouch: DC_title_series")
+	assert.Nil(t, m["DC_publisher"], "ouch: DC_publisher")
+
+	bcs, err := ReadBroadcastsJSON(buf)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(bcs), "ouch")
+	b := bcs[0]
+	assert.Equal(t, "b2", b.Station.Identifier, "ouch: Station.Identifier")
+	assert.Equal(t, bc.Title, b.Title, "ouch: Title")
+	assert.Equal(t, bc.Source.String(), b.Source.String(), "ouch: Source")
+	assert.True(t, bc.Time.Equal(b.Time), "ouch: Time")
+	assert.True(t, bc.DtEnd.Equal(*b.DtEnd), "ouch: DtEnd")
+	assert.True(t, bc.Modified.Equal(*b.Modified), "ouch: Modified")
+	assert.Equal(t, bc.Subject.String(), b.Subject.String(), "ouch: Subject")
+	assert.Equal(t, bc.Image.String(), b.Image.String(), "ouch: Image")
+	assert.Nil(t, b.TitleSeries, "ouch: TitleSeries")
+	assert.Equal(t, episode, *b.TitleEpisode, "ouch: TitleEpisode")
+	assert.Equal(t, description, *b.Description, "ouch: Description")
+	assert.Equal(t, author, *b.Author, "ouch: Author")
+	assert.Equal(t, "de", *b.Language, "ouch: Language")
+	assert.Nil(t, b.Publisher, "ouch: Publisher")
+}
+
+func TestWriteAsJSONMissingIdentifier(t *testing.T) {
+	err := Broadcast{}.WriteAsJSON(new(bytes.Buffer))
+	assert.NotNil(t, err, "ouch")
+}
+
+func TestReadBroadcastsJSONLines(t *testing.T) {
+	src := `{"station":"dlf","title":"Nachrichten","DC_scheme":"/app/pbmi2003-recmod2012/","DC_format_timestart":"2015-10-25T00:00:00+02:00","DC_source":"http://www.deutschlandfunk.de/programmvorschau.281.de.html#0000"}
+{"station":"dlf","title":"Radionacht","DC_scheme":"/app/pbmi2003-recmod2012/","DC_format_timestart":"2015-10-25T02:05:00+01:00","DC_format_timeend":"2015-10-25T06:00:00+01:00","DC_format_duration":14100,"DC_source":"http://www.deutschlandfunk.de/programmvorschau.281.de.html#0205"}
+`
+	bcs, err := ReadBroadcastsJSON(strings.NewReader(src))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(bcs), "ouch")
+	assert.Equal(t, "Nachrichten", bcs[0].Title, "ouch: Title")
+	assert.Nil(t, bcs[0].DtEnd, "ouch: DtEnd")
+	assert.Equal(t, "0205", bcs[1].Source.Fragment, "ouch: Source")
+	assert.Equal(t, 14100*time.Second, bcs[1].DtEnd.Sub(bcs[1].Time), "ouch: Duration")
+
+	_, err = ReadBroadcastsJSON(strings.NewReader(`{"title":"no station"}`))
+	assert.NotNil(t, err, "ouch")
+}
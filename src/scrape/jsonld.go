@@ -0,0 +1,289 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// schema.org JSON-LD of broadcasts embedded in HTML.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// schema.org values may be a string, an object or an array of either.
+type ldThings []ldThing
+
+type ldThing struct {
+	Type          ldTypes  `json:"@type"`
+	Graph         ldThings `json:"@graph"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	URL           string   `json:"url"`
+	ContentURL    string   `json:"contentUrl"`
+	InLanguage    string   `json:"inLanguage"`
+	StartDate     string   `json:"startDate"`
+	EndDate       string   `json:"endDate"`
+	Image         ldThings `json:"image"`
+	Author        ldThings `json:"author"`
+	Creator       ldThings `json:"creator"`
+	PartOfSeries  ldThings `json:"partOfSeries"`
+	Publication   ldThings `json:"publication"`   // of an episode
+	WorkPerformed ldThings `json:"workPerformed"` // of an event
+}
+
+type ldTypes []string
+
+func (ts *ldTypes) UnmarshalJSON(b []byte) error {
+	var s string
+	if nil == json.Unmarshal(b, &s) {
+		*ts = ldTypes{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(ts))
+}
+
+func (ts ldTypes) is(types ...string) bool {
+	for _, t := range ts {
+		for _, want := range types {
+			if want == t || "http://schema.org/"+want == t || "https://schema.org/"+want == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A plain string is taken as both name and url, e.g. "image": "http://..."
+// or "author": "Bayerischer Rundfunk".
+func (ts *ldThings) UnmarshalJSON(b []byte) error {
+	var s string
+	if nil == json.Unmarshal(b, &s) {
+		*ts = ldThings{{Name: s, URL: s}}
+		return nil
+	}
+	var raws []json.RawMessage
+	if nil != json.Unmarshal(b, &raws) {
+		raws = []json.RawMessage{b}
+	}
+	for _, raw := range raws {
+		var one ldThings
+		if "null" == string(raw) {
+			continue
+		}
+		if '[' == raw[0] || '"' == raw[0] {
+			if err := one.UnmarshalJSON(raw); nil != err {
+				return err
+			}
+		} else {
+			var t ldThing
+			if err := json.Unmarshal(raw, &t); nil != err {
+				return err
+			}
+			one = ldThings{t}
+		}
+		*ts = append(*ts, one...)
+	}
+	return nil
+}
+
+func (ts ldThings) names() *string {
+	names := []string{}
+	for _, t := range ts {
+		if n := strings.TrimSpace(t.Name); "" != n {
+			names = append(names, n)
+		}
+	}
+	if 0 == len(names) {
+		return nil
+	}
+	ret := strings.Join(names, ", ")
+	return &ret
+}
+
+func (ts ldThings) url() string {
+	for _, t := range ts {
+		for _, u := range []string{t.ContentURL, t.URL} {
+			if "" != strings.TrimSpace(u) {
+				return strings.TrimSpace(u)
+			}
+		}
+	}
+	return ""
+}
+
+// A BroadcastEvent and the episode it's of, if any.
+type ldEvent struct {
+	event   ldThing
+	episode *ldThing
+}
+
+func collectEvents(ts ldThings, episode *ldThing, ret []ldEvent) []ldEvent {
+	for i := range ts {
+		t := &ts[i]
+		ret = collectEvents(t.Graph, nil, ret)
+		switch {
+		case t.Type.is("BroadcastEvent", "PublicationEvent"):
+			ep := episode
+			if 0 < len(t.WorkPerformed) {
+				ep = &t.WorkPerformed[0]
+			}
+			ret = append(ret, ldEvent{event: *t, episode: ep})
+		case t.Type.is("RadioEpisode", "Episode", "RadioProgram", "CreativeWork"):
+			ret = collectEvents(t.Publication, t, ret)
+		}
+	}
+	return ret
+}
+
+// ISO 8601 as seen in the wild, with or without seconds and zone.
+func parseLDTime(s string, loc *time.Location) (t time.Time, err error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err = time.Parse(layout, s); nil == err {
+			return t.In(loc), nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err = time.ParseInLocation(layout, s, loc); nil == err {
+			return
+		}
+	}
+	return
+}
+
+// The first non-empty of ss.
+func first(ss ...string) string {
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); "" != s {
+			return s
+		}
+	}
+	return ""
+}
+
+func (e ldEvent) broadcast(bc Broadcast, script *html.Node) (Broadcast, error) {
+	ep := e.event
+	if nil != e.episode {
+		ep = *e.episode
+	}
+	loc := bc.Station.TimeZone
+	if nil == loc {
+		loc = time.UTC
+	}
+	var err error
+	if bc.Time, err = parseLDTime(e.event.StartDate, loc); nil != err {
+		return bc, NewParseError(bc.Source, script, err)
+	}
+	if "" != e.event.EndDate {
+		t, err := parseLDTime(e.event.EndDate, loc)
+		if nil != err {
+			return bc, NewParseError(bc.Source, script, err)
+		}
+		bc.DtEnd, bc.DtEndGuessed = &t, false
+	}
+	if s := first(ep.Name, e.event.Name); "" != s {
+		bc.Title = s
+	}
+	if s := first(ep.Description, e.event.Description); "" != s {
+		bc.Description = &s
+	}
+	if s := first(ep.InLanguage, e.event.InLanguage); "" != s {
+		bc.Language = &s
+	}
+	for _, series := range []ldThings{ep.PartOfSeries, e.event.PartOfSeries} {
+		if s := series.names(); nil != s {
+			bc.TitleSeries = s
+			break
+		}
+	}
+	if s := first(ep.Image.url(), e.event.Image.url()); "" != s {
+		u, err := url.Parse(s)
+		if nil != err {
+			return bc, NewParseError(bc.Source, script, err)
+		}
+		bc.Image = bc.Source.ResolveReference(u)
+	}
+	for _, a := range []ldThings{ep.Author, e.event.Author} {
+		if s := a.names(); nil != s {
+			bc.Author = s
+			break
+		}
+	}
+	for _, c := range []ldThings{ep.Creator, e.event.Creator} {
+		if s := c.names(); nil != s {
+			bc.Creator = s
+			break
+		}
+	}
+	return bc, nil
+}
+
+// The schema.org BroadcastEvents (or RadioEpisodes with their publication)
+// in the application/ld+json scripts below root, each one bc with what the
+// JSON-LD tells: startDate, endDate, name, description, partOfSeries, image,
+// author and creator. Blocks that don't decode and events that don't map are
+// skipped, JSON-LD is a bonus, the page's HTML is what counts.
+func BroadcastsFromJSONLD(root *html.Node, bc Broadcast) (ret []Broadcast) {
+	for _, script := range scrape.FindAll(root, func(n *html.Node) bool {
+		return atom.Script == n.DataAtom && "application/ld+json" == strings.ToLower(strings.TrimSpace(scrape.Attr(n, "type")))
+	}) {
+		var ts ldThings
+		if nil == script.FirstChild {
+			continue
+		}
+		if err := json.Unmarshal([]byte(script.FirstChild.Data), &ts); nil != err {
+			fmt.Fprintf(os.Stderr, "ignored %s\n", NewParseError(bc.Source, script, err))
+			continue
+		}
+		for _, e := range collectEvents(ts, nil, nil) {
+			if "" == strings.TrimSpace(e.event.StartDate) {
+				continue
+			}
+			b, err := e.broadcast(bc, script)
+			if nil != err {
+				fmt.Fprintf(os.Stderr, "ignored %s\n", err)
+				continue
+			}
+			ret = append(ret, b)
+		}
+	}
+	return
+}
+
+// bc with what the JSON-LD event starting at t tells, else bc as is. Detail
+// page parsers call it last to prefer JSON-LD wherever present. Other dates
+// the page may list are none of our business.
+func PreferJSONLD(root *html.Node, bc Broadcast, t time.Time) Broadcast {
+	for _, b := range BroadcastsFromJSONLD(root, bc) {
+		if b.Time.Equal(t) {
+			return b
+		}
+	}
+	return bc
+}
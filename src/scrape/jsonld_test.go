@@ -0,0 +1,146 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestBroadcastsFromJSONLD(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	desc, author := "aus dem HTML", "BR"
+	proto := Broadcast{
+		BroadcastURL: BroadcastURL{
+			TimeURL: TimeURL{
+				Time:    time.Date(2016, time.November, 27, 20, 30, 0, 0, tz),
+				Source:  *MustParseURL("https://www.br-klassik.de/programm/radio/ausstrahlung-914548.html"),
+				Station: Station{Identifier: "b4", TimeZone: tz},
+			},
+			Title: "aus dem HTML",
+		},
+		Description: &desc,
+		Author:      &author,
+	}
+	parse := func(ld string) []Broadcast {
+		return BroadcastsFromJSONLD(page(t, ld), proto)
+	}
+
+	bcs := parse(`<script type="application/ld+json">
+{
+  "@context": "http://schema.org",
+  "@type": "RadioEpisode",
+  "name": "Intermezzo",
+  "description": "Ernst von Gemmingen: Violinkonzert Nr. 1 A-Dur",
+  "inLanguage": "de",
+  "partOfSeries": { "@type": "RadioSeries", "name": "Konzert am Abend" },
+  "image": { "@type": "ImageObject", "url": "/img/intermezzo.jpg" },
+  "author": [ { "@type": "Person", "name": "Kolja Lessing" }, { "@type": "Person", "name": "Ulf Schirmer" } ],
+  "creator": "Bayerischer Rundfunk",
+  "publication": {
+    "@type": "BroadcastEvent",
+    "startDate": "2016-11-27T19:30:00Z",
+    "endDate": "2016-11-27T21:00:00+01:00"
+  }
+}
+</script>`)
+	assert.Equal(t, 1, len(bcs), "ouch")
+	bc := bcs[0]
+	assert.Equal(t, "Intermezzo", bc.Title, "JSON-LD beats HTML")
+	assert.Equal(t, "2016-11-27T20:30:00+01:00", bc.Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, tz, bc.Time.Location(), "the station's")
+	assert.Equal(t, "2016-11-27T21:00:00+01:00", bc.DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "Ernst von Gemmingen: Violinkonzert Nr. 1 A-Dur", *bc.Description, "ouch")
+	assert.Equal(t, "Konzert am Abend", *bc.TitleSeries, "partOfSeries")
+	assert.Equal(t, "https://www.br-klassik.de/img/intermezzo.jpg", bc.Image.String(), "relative to Source")
+	assert.Equal(t, "Kolja Lessing, Ulf Schirmer", *bc.Author, "ouch")
+	assert.Equal(t, "Bayerischer Rundfunk", *bc.Creator, "plain string")
+	assert.Equal(t, "de", *bc.Language, "ouch")
+	assert.Equal(t, "b4", bc.Station.Identifier, "from proto")
+	assert.Equal(t, proto.Source, bc.Source, "from proto")
+	assert.Equal(t, "aus dem HTML", desc, "proto untouched")
+
+	bcs = parse(`<script type="application/ld+json">{"@context": "https://schema.org", "@graph": [
+  { "@type": "WebPage", "name": "Programm" },
+  { "@type": ["BroadcastEvent"], "name": "Nachrichten", "startDate": "2016-11-27T20:00", "workPerformed": { "@type": "CreativeWork", "name": "BR24 Nachrichten" } },
+  { "@type": "https://schema.org/BroadcastEvent", "startDate": "2016-11-27T21:00:00+01:00", "image": ["http://example.com/a.jpg", "http://example.com/b.jpg"] }
+]}</script>`)
+	assert.Equal(t, 2, len(bcs), "events only")
+	assert.Equal(t, "BR24 Nachrichten", bcs[0].Title, "the work performed")
+	assert.Equal(t, "2016-11-27T20:00:00+01:00", bcs[0].Time.Format(time.RFC3339), "no zone, the station's")
+	assert.Nil(t, bcs[0].DtEnd, "ouch")
+	assert.Equal(t, "aus dem HTML", *bcs[0].Description, "kept")
+	assert.Equal(t, "BR", *bcs[0].Author, "kept")
+	assert.Equal(t, "aus dem HTML", bcs[1].Title, "kept")
+	assert.Equal(t, "http://example.com/a.jpg", bcs[1].Image.String(), "the first")
+
+	for _, ld := range []string{
+		``,
+		`<script type="text/javascript">var x = 1;</script>`,
+		`<script type="application/ld+json">{"@type": "WebPage", "name": "Programm"}</script>`,
+		`<script type="application/ld+json">{"@type": "BroadcastEvent", "name": "no start"}</script>`,
+		`<script type="application/ld+json">{"@type": "BroadcastEvent",</script>`,
+		`<script type="application/ld+json">{"@type": "BroadcastEvent", "startDate": "27.11.2016 20:30"}</script>`,
+	} {
+		assert.Equal(t, 0, len(parse(ld)), ld)
+	}
+
+	bcs = parse(`<script type="application/ld+json">{"@type": "BroadcastEvent",</script>
+<script type="application/ld+json">{"@type": "BroadcastEvent", "name": "Intermezzo", "startDate": "2016-11-27T20:30:00+01:00"}</script>`)
+	assert.Equal(t, 1, len(bcs), "a broken block doesn't spoil the others")
+}
+
+func TestPreferJSONLD(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	t0 := time.Date(2016, time.November, 27, 20, 30, 0, 0, tz)
+	proto := Broadcast{}
+	proto.Station = Station{Identifier: "b4", TimeZone: tz}
+	proto.Time = t0
+	proto.Title = "aus dem HTML"
+	root := page(t, `<script type="application/ld+json">{"@type": "RadioEpisode", "name": "Intermezzo", "publication": [
+	{"@type": "BroadcastEvent", "startDate": "2016-11-20T20:30:00+01:00"},
+	{"@type": "BroadcastEvent", "startDate": "2016-11-27T20:30:00+01:00", "endDate": "2016-11-27T21:00:00+01:00"}
+]}</script>`)
+
+	bc := PreferJSONLD(root, proto, t0)
+	assert.Equal(t, "Intermezzo", bc.Title, "ouch")
+	assert.Equal(t, "2016-11-27T21:00:00+01:00", bc.DtEnd.Format(time.RFC3339), "the one at t0")
+
+	bc = PreferJSONLD(root, proto, t0.Add(time.Hour))
+	assert.Equal(t, proto, bc, "none at t, the HTML one")
+
+	bc = PreferJSONLD(page(t, `<script type="application/ld+json">{"@type": "BroadcastEvent",</script>`), proto, t0)
+	assert.Equal(t, proto, bc, "broken JSON-LD, the HTML one")
+}
+
+func page(t *testing.T, head string) *html.Node {
+	root, err := html.Parse(strings.NewReader(`<html><head><title>t</title>` + head + `</head><body><p>x</p></body></html>`))
+	assert.Nil(t, err, "ouch")
+	return root
+}
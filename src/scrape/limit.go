@@ -0,0 +1,141 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Be polite: limit parallel requests and request rate per host.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type HostLimit struct {
+	Concurrency int           // parallel requests incl. reading the body, 0: unlimited
+	Interval    time.Duration // min. time between two request starts, 0: none
+}
+
+// For hosts without SetHostLimit.
+var DefaultHostLimit = HostLimit{Concurrency: 2}
+
+// The stricter of both in each field, e.g. for stations sharing a host.
+func (l HostLimit) strictest(o HostLimit) HostLimit {
+	if 0 == l.Concurrency || (0 < o.Concurrency && o.Concurrency < l.Concurrency) {
+		l.Concurrency = o.Concurrency
+	}
+	if l.Interval < o.Interval {
+		l.Interval = o.Interval
+	}
+	return l
+}
+
+type host struct {
+	mu    sync.Mutex // guards all below
+	limit HostLimit
+	set   bool // by SetHostLimit rather than DefaultHostLimit
+	slots chan struct{}
+	next  time.Time // earliest start of the next request
+}
+
+var hosts = struct {
+	sync.Mutex
+	m map[string]*host
+}{m: make(map[string]*host)}
+
+func newHost(l HostLimit) *host {
+	h := &host{limit: l}
+	if 0 < l.Concurrency {
+		h.slots = make(chan struct{}, l.Concurrency)
+	}
+	return h
+}
+
+// Limit requests to host (as in url.URL.Host). Call before scraping starts.
+// Stations sharing a host get the strictest of their limits, so setting one
+// again is a no-op. Requests under way keep their slot if Concurrency changes.
+func SetHostLimit(host string, l HostLimit) {
+	hosts.Lock()
+	defer hosts.Unlock()
+	h, ok := hosts.m[host]
+	if !ok {
+		h = newHost(l)
+		h.set = true
+		hosts.m[host] = h
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.set {
+		l = h.limit.strictest(l)
+	}
+	if l.Concurrency != h.limit.Concurrency {
+		h.slots = nil
+		if 0 < l.Concurrency {
+			h.slots = make(chan struct{}, l.Concurrency)
+		}
+	}
+	h.limit, h.set = l, true
+}
+
+func hostFor(name string) *host {
+	hosts.Lock()
+	defer hosts.Unlock()
+	h, ok := hosts.m[name]
+	if !ok {
+		h = newHost(DefaultHostLimit)
+		hosts.m[name] = h
+	}
+	return h
+}
+
+// Block until a request may start. Call release when done with it.
+func (h *host) acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	h.mu.Lock()
+	slots, interval := h.slots, h.limit.Interval
+	h.mu.Unlock()
+	if nil != slots {
+		select {
+		case slots <- struct{}{}:
+			release = func() { <-slots } // the one taken from, even if replaced meanwhile
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if 0 < interval {
+		h.mu.Lock()
+		now := time.Now()
+		at := h.next
+		if at.Before(now) {
+			at = now
+		}
+		h.next = at.Add(interval)
+		h.mu.Unlock()
+		select {
+		case <-time.After(at.Sub(now)):
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return
+}
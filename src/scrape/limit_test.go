@@ -0,0 +1,146 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counts concurrent connections and remembers the maximum
+func concurrencyServer(agents chan<- string) (*httptest.Server, *int32) {
+	var current, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		if nil != agents {
+			agents <- r.Header.Get("User-Agent")
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("<html/>"))
+	}))
+	return srv, &max
+}
+
+func fetchParallel(t *testing.T, u string, n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bo, _, err := HttpGetBody(context.Background(), *MustParseURL(u))
+			assert.Nil(t, err, "ouch")
+			ioutil.ReadAll(bo)
+			bo.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHostLimitConcurrency(t *testing.T) {
+	srv, max := concurrencyServer(nil)
+	defer srv.Close()
+	SetHostLimit(MustParseURL(srv.URL).Host, HostLimit{Concurrency: 3})
+
+	fetchParallel(t, srv.URL, 12)
+	assert.Equal(t, int32(3), atomic.LoadInt32(max), "ouch")
+}
+
+func TestHostLimitDefault(t *testing.T) {
+	srv, max := concurrencyServer(nil)
+	defer srv.Close()
+
+	fetchParallel(t, srv.URL, 8)
+	assert.Equal(t, int32(DefaultHostLimit.Concurrency), atomic.LoadInt32(max), "ouch")
+}
+
+func TestHostLimitInterval(t *testing.T) {
+	srv, max := concurrencyServer(nil)
+	defer srv.Close()
+	SetHostLimit(MustParseURL(srv.URL).Host, HostLimit{Interval: 50 * time.Millisecond})
+
+	t0 := time.Now()
+	fetchParallel(t, srv.URL, 4)
+	assert.True(t, 150*time.Millisecond <= time.Since(t0), "3 pauses")
+	assert.Equal(t, int32(1), atomic.LoadInt32(max), "20ms requests 50ms apart")
+}
+
+// e.g. b2 and b5 both on www.br.de
+func TestHostLimitShared(t *testing.T) {
+	name := "shared.example.com"
+	SetHostLimit(name, HostLimit{Concurrency: 2, Interval: 250 * time.Millisecond})
+	h := hostFor(name)
+	SetHostLimit(name, HostLimit{Concurrency: 4, Interval: 500 * time.Millisecond})
+	SetHostLimit(name, HostLimit{Concurrency: 3})
+	assert.Equal(t, h, hostFor(name), "kept")
+	assert.Equal(t, HostLimit{Concurrency: 2, Interval: 500 * time.Millisecond}, h.limit, "the strictest")
+
+	release, err := h.acquire(context.Background())
+	assert.Nil(t, err, "ouch")
+	SetHostLimit(name, HostLimit{Concurrency: 1})
+	assert.Equal(t, 0, len(h.slots), "a new one")
+	release()
+	assert.Equal(t, 0, len(h.slots), "released into the old one")
+
+	assert.Equal(t, HostLimit{Interval: time.Second}, HostLimit{}.strictest(HostLimit{Interval: time.Second}), "0 is unlimited")
+}
+
+func TestHostLimitCancel(t *testing.T) {
+	srv, _ := concurrencyServer(nil)
+	defer srv.Close()
+	h := MustParseURL(srv.URL).Host
+	SetHostLimit(h, HostLimit{Concurrency: 1})
+
+	release, err := hostFor(h).acquire(context.Background())
+	assert.Nil(t, err, "ouch")
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err = HttpGetBody(ctx, *MustParseURL(srv.URL))
+	assert.Equal(t, context.DeadlineExceeded, err, "waiting for a slot honours ctx")
+}
+
+func TestUserAgent(t *testing.T) {
+	agents := make(chan string, 1)
+	srv, _ := concurrencyServer(agents)
+	defer srv.Close()
+
+	fetchParallel(t, srv.URL, 1)
+	assert.Equal(t, UserAgent, <-agents, "ouch")
+}
@@ -20,14 +20,10 @@
 package m945 // import "purl.mro.name/recorder/radio/scrape/m945"
 
 import (
+	"context"
 	"io"
-	"net/url"
-	"strings"
 	"time"
 
-	"github.com/yhat/scrape"
-	"golang.org/x/net/html"
-	"golang.org/x/net/html/atom"
 	r "purl.mro.name/recorder/radio/scrape"
 )
 
@@ -47,6 +43,30 @@ func Station(identifier string) *station {
 	return nil
 }
 
+func init() {
+	r.Register(r.Station(*Station("m945")), func(st r.Station) r.Scraper { s := station(st); return &s })
+	r.RegisterLevel("m945", "day", func(tu r.TimeURL) r.Scraper { return timeURL(tu) })
+}
+
+// http://www.m945.de/programm/?daterequest=2015-11-14
+//
+//	<div class="item bg">
+//	<div class="time">11:00</div>
+//	<div class="descr"><a href="http://www.m945.de/content/katerfruhstuck.html">Katerfr&uuml;hst&uuml;ck</a>
+//	 Dein guter Morgen auf M94.5</div>
+//	</div>
+var program = r.DayList{
+	DayURL:      "?daterequest=2006-01-02",
+	Item:        "div.item",
+	Time:        "div.time",
+	TimeFormat:  "15:04",
+	Title:       "div.descr > a",
+	Link:        "div.descr > a",
+	Description: "div.descr",
+	Publisher:   "http://www.m945.de/",
+	Language:    "de",
+}
+
 ///////////////////////////////////////////////////////////////////////
 /// r.Scraper
 
@@ -55,27 +75,22 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // Synthesise calItemRangeURLs for incremental scraping and queue them up
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	now := time.Now()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	now := r.Now()
 	for _, t0 := range r.IncrementalNows(now) {
-		day, _ := s.dayURLForDate(t0)
+		day, err := s.dayURLForDate(t0)
+		if nil != err {
+			return nil, nil, err
+		}
 		jobs = append(jobs, r.Scraper(*day))
 	}
 	return
 }
 
-///////////////////////////////////////////////////////////////////////
-// http://www.m945.de/programm/?daterequest=2015-11-14
-
 func (s *station) dayURLForDate(day time.Time) (ret *timeURL, err error) {
-	r := timeURL(r.TimeURL{
-		Time:    time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.TimeZone),
-		Source:  *r.MustParseURL(s.ProgramURL.String() + day.Format("?daterequest=2006-01-02")),
-		Station: r.Station(*s),
-	})
-	ret = &r
-	// err = errors.New("Not ümplemented yet.")
-	return
+	tu, err := program.Day(r.Station(*s), day)
+	t := timeURL(tu)
+	return &t, err
 }
 
 /////////////////////////////////////////////////////////////////////////////
@@ -88,88 +103,10 @@ func (day timeURL) Matches(nows []time.Time) (ok bool) {
 }
 
 // Scrape broadcasts from a day page.
-func (day timeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := day.parseBroadcastsFromURL()
-	if nil == err {
-		for _, bc := range bcs {
-			results = append(results, bc)
-		}
-	}
-	return
-}
-
-var (
-	langDe    string = "de"
-	publisher string = "http://www.m945.de/"
-)
-
-func (day *timeURL) parseBroadcastsFromNode(root *html.Node) (ret []*r.Broadcast, err error) {
-	nodes := scrape.FindAll(root, func(n *html.Node) bool { return atom.Div == n.DataAtom && "time" == scrape.Attr(n, "class") })
-	ret = make([]*r.Broadcast, len(nodes))
-	for index, tim := range nodes {
-		// prepare response
-		bc := r.Broadcast{
-			BroadcastURL: r.BroadcastURL{
-				TimeURL: r.TimeURL(*day),
-			},
-		}
-		// some defaults
-		bc.Language = &langDe
-		bc.Publisher = &publisher
-		// set start time
-		{
-			divT := strings.TrimSpace(scrape.Text(tim))
-			if 5 != len(divT) {
-				continue
-			}
-			hour := r.MustParseInt(divT[0:2])
-			minute := r.MustParseInt(divT[3:5])
-			bc.Time = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.TimeZone)
-			if index > 0 {
-				ret[index-1].DtEnd = &bc.Time
-			}
-		}
-		for _, tit := range scrape.FindAll(tim.Parent, func(n *html.Node) bool {
-			return atom.A == n.DataAtom && atom.Div == n.Parent.DataAtom && "descr" == scrape.Attr(n.Parent, "class")
-		}) {
-			// Title
-			bc.Title = strings.TrimSpace(scrape.Text(tit))
-			href := scrape.Attr(tit, "href")
-			if "" != href {
-				u, _ := url.Parse(href)
-				bc.Subject = day.Source.ResolveReference(u)
-			}
-
-			descNode := tit.Parent
-			descNode.RemoveChild(tit)
-			description := r.TextWithBrFromNodeSet([]*html.Node{descNode})
-			bc.Description = &description
-			// fmt.Fprintf(os.Stderr, "\n")
-		}
-		ret[index] = &bc
-	}
-	// fmt.Fprintf(os.Stderr, "len(ret) = %d '%s'\n", len(ret), day.Source.String())
-	if len(nodes) > 0 {
-		midnight := time.Date(day.Year(), day.Month(), day.Day(), 24, 0, 0, 0, day.TimeZone)
-		ret[len(nodes)-1].DtEnd = &midnight
-	}
-	return
+func (day timeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	return program.DayScraper(r.TimeURL(day)).Scrape(ctx)
 }
 
 func (day *timeURL) parseBroadcastsFromReader(read io.Reader, cr0 *r.CountingReader) (ret []*r.Broadcast, err error) {
-	cr := r.NewCountingReader(read)
-	root, err := html.Parse(cr)
-	r.ReportLoad("🐦", cr0, cr, day.Source)
-	if nil != err {
-		return
-	}
-	return day.parseBroadcastsFromNode(root)
-}
-
-func (day *timeURL) parseBroadcastsFromURL() (ret []*r.Broadcast, err error) {
-	bo, cr, err := r.HttpGetBody(day.Source)
-	if nil == bo {
-		return nil, err
-	}
-	return day.parseBroadcastsFromReader(bo, cr)
+	return program.ParseReader(r.TimeURL(*day), read, cr0)
 }
@@ -44,6 +44,22 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", s.TimeZone.String(), "ouch: TimeZone")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("m945"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 20, len(bcs), "today twice, for now and now + 12h")
+	first := bcs[0]
+	last := bcs[9]
+	assert.Equal(t, "m945", first.Station.Identifier, "ouch")
+	assert.Equal(t, "Black Box: HipHop", first.Title, "ouch")
+	assert.Equal(t, "2015-11-14T00:00:00+01:00", first.Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "Musik 22:00-00:00", last.Title, "ouch")
+	assert.Equal(t, "2015-11-15T00:00:00+01:00", last.DtEnd.Format(time.RFC3339), "ouch")
+	assert.True(t, last.DtEndGuessed, "until the next day tells")
+	assert.False(t, first.DtEndGuessed, "ouch")
+}
+
 func TestDayURLForDate(t *testing.T) {
 	s := Station("m945")
 	u, err := s.dayURLForDate(time.Date(2015, 11, 30, 5, 0, 0, 0, s.TimeZone))
@@ -0,0 +1,159 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Merge the broadcasts of overlapping scrapes, see Merger.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Two scrapes disagree about a slot. The newer one wins, see Merge.
+type Conflict struct {
+	Station string
+	Time    time.Time
+	Field   string
+	Kept    string
+	Dropped string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %s %s: '%s' over '%s'", c.Station, c.Time.Format("2006-01-02 15:04 MST"), c.Field, c.Kept, c.Dropped)
+}
+
+// Combine two scrapes of the same slot (station and start). Fields missing
+// in one are taken from the other, the Description is the longer one and
+// Modified the later one, a known DtEnd beats a guessed one. Differing Title
+// or DtEnd are Conflicts, the more recently Modified broadcast (or else a) wins.
+func Merge(a, b Broadcast) (ret Broadcast, conflicts []Conflict) {
+	if nil != b.Modified && (nil == a.Modified || b.Modified.After(*a.Modified)) {
+		a, b = b, a
+	}
+	ret = a
+	conflict := func(field, kept, dropped string) {
+		conflicts = append(conflicts, Conflict{Station: a.Station.Identifier, Time: a.Time, Field: field, Kept: kept, Dropped: dropped})
+	}
+	if "" == ret.Title {
+		ret.Title = b.Title
+	} else if "" != b.Title && ret.Title != b.Title {
+		conflict("title", ret.Title, b.Title)
+	}
+	if nil == ret.DtEnd || (ret.DtEndGuessed && nil != b.DtEnd && !b.DtEndGuessed) {
+		ret.DtEnd, ret.DtEndGuessed = b.DtEnd, b.DtEndGuessed
+	} else if nil != b.DtEnd && !ret.DtEndGuessed && !b.DtEndGuessed && !ret.DtEnd.Equal(*b.DtEnd) {
+		conflict("end", ret.DtEnd.Format("15:04"), b.DtEnd.Format("15:04"))
+	}
+	if nil == ret.Description || (nil != b.Description && len(*b.Description) > len(*ret.Description)) {
+		ret.Description = b.Description
+	}
+	strs := []struct {
+		dst **string
+		src *string
+	}{
+		{&ret.TitleSeries, b.TitleSeries},
+		{&ret.TitleEpisode, b.TitleEpisode},
+		{&ret.Author, b.Author},
+		{&ret.Language, b.Language},
+		{&ret.Publisher, b.Publisher},
+		{&ret.Creator, b.Creator},
+		{&ret.Copyright, b.Copyright},
+	}
+	for _, s := range strs {
+		if nil == *s.dst {
+			*s.dst = s.src
+		}
+	}
+	if nil == ret.Subject {
+		ret.Subject = b.Subject
+	}
+	if nil == ret.Image {
+		ret.Image = b.Image
+	}
+	return
+}
+
+type mergeKey struct {
+	station string
+	start   int64
+}
+
+// Collects broadcasts, one per station and start, safe for concurrent use.
+type Merger struct {
+	mu         sync.Mutex
+	byKey      map[mergeKey]*Broadcast
+	duplicates int
+	conflicts  []Conflict
+}
+
+func NewMerger() *Merger {
+	return &Merger{byKey: make(map[mergeKey]*Broadcast)}
+}
+
+// Add b or Merge it into the one already there for the slot.
+func (m *Merger) Add(b Broadcast) {
+	k := mergeKey{station: b.Station.Identifier, start: b.Time.Unix()}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.byKey[k]
+	if !ok {
+		m.byKey[k] = &b
+		return
+	}
+	merged, conflicts := Merge(*old, b)
+	*old = merged
+	m.duplicates++
+	m.conflicts = append(m.conflicts, conflicts...)
+}
+
+// How many broadcasts were merged into one already there.
+func (m *Merger) Duplicates() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.duplicates
+}
+
+// The conflicts so far, see Merge.
+func (m *Merger) Conflicts() []Conflict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Conflict(nil), m.conflicts...)
+}
+
+// All of them, ordered by station and start.
+func (m *Merger) Broadcasts() (ret []Broadcast) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ret = make([]Broadcast, 0, len(m.byKey))
+	for _, b := range m.byKey {
+		ret = append(ret, *b)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		if ret[a].Station.Identifier != ret[b].Station.Identifier {
+			return ret[a].Station.Identifier < ret[b].Station.Identifier
+		}
+		return ret[a].Time.Before(ret[b].Time)
+	})
+	return
+}
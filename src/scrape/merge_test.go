@@ -0,0 +1,156 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t0 := time.Date(2015, time.October, 21, 5, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour), t0.Add(2*time.Hour)
+	short, long := "kurz", "die längere Beschreibung"
+	mod0, mod1 := t0.Add(-48*time.Hour), t0.Add(-24*time.Hour)
+	author := "mro"
+
+	a := Broadcast{}
+	a.Station.Identifier = "b2"
+	a.Time = t0
+	a.Title = "Alt"
+	a.DtEnd = &t1
+	a.Description = &long
+	a.Modified = &mod0
+	a.Author = &author
+
+	b := Broadcast{}
+	b.Station.Identifier = "b2"
+	b.Time = t0
+	b.Title = "Neu"
+	b.DtEnd = &t2
+	b.Description = &short
+	b.Modified = &mod1
+
+	m, cs := Merge(a, b)
+	assert.Equal(t, "Neu", m.Title, "newer wins")
+	assert.Equal(t, t2, *m.DtEnd, "newer wins")
+	assert.Equal(t, long, *m.Description, "richest")
+	assert.Equal(t, mod1, *m.Modified, "latest")
+	assert.Equal(t, &author, m.Author, "filled in")
+	assert.Equal(t, 2, len(cs), "ouch")
+	assert.Equal(t, "b2 2015-10-21 05:00 UTC title: 'Neu' over 'Alt'", cs[0].String(), "ouch")
+	assert.Equal(t, "b2 2015-10-21 05:00 UTC end: '07:00' over '06:00'", cs[1].String(), "ouch")
+
+	m1, cs := Merge(b, a)
+	assert.Equal(t, m, m1, "order doesn't matter")
+
+	b.Title, b.DtEnd, b.Modified = "", nil, nil
+	m, cs = Merge(a, b)
+	assert.Equal(t, "Alt", m.Title, "ouch")
+	assert.Equal(t, t1, *m.DtEnd, "ouch")
+	assert.Equal(t, 0, len(cs), "nothing to disagree about")
+}
+
+func TestMergeGuessedEnd(t *testing.T) {
+	t0 := time.Date(2015, time.October, 24, 23, 0, 0, 0, time.UTC)
+	guess, known := t0.Add(time.Hour), t0.Add(90*time.Minute)
+	a := Broadcast{}
+	a.Station.Identifier = "rf"
+	a.Time, a.Title = t0, "Nacht"
+	a.DtEnd, a.DtEndGuessed = &guess, true
+	b := a
+	b.DtEnd, b.DtEndGuessed = &known, false
+
+	m, cs := Merge(a, b)
+	assert.Equal(t, known, *m.DtEnd, "ouch")
+	assert.False(t, m.DtEndGuessed, "ouch")
+	assert.Equal(t, 0, len(cs), "a guess is no conflict")
+	m, cs = Merge(b, a)
+	assert.Equal(t, known, *m.DtEnd, "ouch")
+	assert.Equal(t, 0, len(cs), "ouch")
+}
+
+func TestMerger(t *testing.T) {
+	t0 := time.Date(2015, time.October, 21, 5, 0, 0, 0, time.UTC)
+	bc := func(station string, start time.Time, title string) Broadcast {
+		b := Broadcast{}
+		b.Station.Identifier = station
+		b.Time = start
+		b.Title = title
+		return b
+	}
+	m := NewMerger()
+	var wg sync.WaitGroup
+	for _, b := range []Broadcast{
+		bc("b2", t0.Add(time.Hour), "Zwei"),
+		bc("b2", t0, "Eins"),
+		bc("b+", t0, "Eins"),
+		bc("b2", t0.In(time.FixedZone("CET", 3600)), "Eins"), // same instant
+		bc("b2", t0.Add(time.Hour), "Drei"),
+	} {
+		wg.Add(1)
+		go func(b Broadcast) { defer wg.Done(); m.Add(b) }(b)
+	}
+	wg.Wait()
+
+	bcs := m.Broadcasts()
+	assert.Equal(t, 3, len(bcs), "ouch")
+	assert.Equal(t, "b+", bcs[0].Station.Identifier, "ouch")
+	assert.Equal(t, "Eins", bcs[1].Title, "ouch")
+	assert.Equal(t, t0.Add(time.Hour), bcs[2].Time, "ouch")
+	assert.Equal(t, 2, m.Duplicates(), "ouch")
+	assert.Equal(t, 1, len(m.Conflicts()), "Zwei vs. Drei")
+	assert.Equal(t, "title", m.Conflicts()[0].Field, "ouch")
+}
+
+// Reading while still adding, as scrape-cmd does after a timeout. Run with -race.
+func TestMergerReadWhileAdding(t *testing.T) {
+	m := NewMerger()
+	t0 := time.Date(2015, time.October, 21, 5, 0, 0, 0, time.UTC)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			b := Broadcast{}
+			b.Station.Identifier = "b2"
+			b.Time = t0
+			b.Title = fmt.Sprintf("%d", i%2)
+			m.Add(b)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		m.Broadcasts()
+		m.Duplicates()
+		for range m.Conflicts() {
+		}
+	}
+	<-done
+	assert.Equal(t, 99, m.Duplicates(), "ouch")
+	assert.Equal(t, 1, len(m.Broadcasts()), "ouch")
+}
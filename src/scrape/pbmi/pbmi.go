@@ -0,0 +1,237 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Read and write the broadcast xml files 'stations/<id>.xml' (DC.* meta rows
+// according /app/pbmi2003-recmod2012/) as scrape.Broadcast.
+//
+// import "purl.mro.name/recorder/radio/scrape/pbmi"
+
+package pbmi
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+const Scheme = "/app/pbmi2003-recmod2012/"
+
+// Mirror string:to_filename from Broadcast.lua
+func ToFilename(s string) string {
+	return strings.NewReplacer("/", "-", "\t", " ", "\n", " ", "–", "-").Replace(s)
+}
+
+// The broadcast id, e.g. 'b2/2016/08/25/1805 Bayern 2-radioMusik'
+func Identifier(bc scrape.Broadcast) string {
+	t := bc.Time
+	if nil != bc.Station.TimeZone {
+		t = t.In(bc.Station.TimeZone)
+	}
+	return bc.Station.Identifier + "/" + t.Format("2006/01/02/1504") + " " + ToFilename(bc.Title)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Serialise
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// Mirror string:escape_xml_attribute from recorder-plumbing.lua
+func escapeXmlAttribute(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;", "\n", "&#10;").Replace(s)
+}
+
+// Write the broadcast xml byte-by-byte like Broadcast.lua save_xml.
+func Write(w io.Writer, bc scrape.Broadcast) (err error) {
+	if "" == bc.Station.Identifier {
+		return errors.New("How can the identifier miss?")
+	}
+	if nil == bc.DtEnd {
+		return errors.New("missing DtEnd for " + bc.Source.String())
+	}
+	dt := bc.DtEnd.Sub(bc.Time) / time.Second
+	if dt < 0 {
+		return errors.New("dt < 0 for " + bc.Source.String())
+	}
+
+	lines := []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<?xml-stylesheet type="text/xsl" href="../../../app/broadcast2html.xslt"?>`,
+		`<!-- unorthodox relative namespace to enable http://www.w3.org/TR/grddl-tests/#sq2 without a central server -->`,
+		`<broadcast xml:lang="de" xmlns="../../../../../assets/2013/radio-pi.rdf">`,
+	}
+	f := func(k string, v string) {
+		lines = append(lines, "    <meta content='"+escapeXmlAttribute(v)+"' name='"+k+"'/>")
+	}
+	fp := func(k string, v *string) {
+		if nil != v {
+			f(k, *v)
+		}
+	}
+	fpu := func(k string, v *url.URL) {
+		if nil != v {
+			f(k, v.String())
+		}
+	}
+
+	f("DC.identifier", Identifier(bc))
+	f("DC.scheme", Scheme)
+	fp("DC.language", bc.Language)
+	f("DC.title", bc.Title)
+	fp("DC.title.series", bc.TitleSeries)
+	fp("DC.title.episode", bc.TitleEpisode)
+	fpu("DC.subject", bc.Subject)
+	f("DC.format.timestart", bc.Time.Format(time.RFC3339))
+	f("DC.format.timeend", bc.DtEnd.Format(time.RFC3339))
+	f("DC.format.duration", strconv.FormatInt(int64(dt), 10))
+	fpu("DC.image", bc.Image)
+	fp("DC.description", bc.Description)
+	fp("DC.author", bc.Author)
+	fp("DC.publisher", bc.Publisher)
+	fp("DC.creator", bc.Creator)
+	fp("DC.copyright", bc.Copyright)
+	f("DC.source", bc.Source.String())
+	lines = append(lines, "</broadcast>")
+
+	_, err = io.WriteString(w, strings.Join(lines, "\n"))
+	return
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Parse
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type xmlEntry struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
+}
+
+type broadcastXml struct {
+	XMLName  xml.Name   `xml:"../../../../../assets/2013/radio-pi.rdf broadcast"`
+	Language string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Meta     []xmlEntry `xml:"meta"`
+}
+
+// Parse a broadcast xml file. Modified is set to the file's modification time.
+func ReadFile(xmlFileName string) (bc scrape.Broadcast, err error) {
+	r, err := os.Open(xmlFileName)
+	if nil != err {
+		return
+	}
+	defer r.Close()
+	if bc, err = Read(r); nil != err {
+		return
+	}
+	if fi, e := r.Stat(); nil == e {
+		mod := fi.ModTime()
+		bc.Modified = &mod
+	}
+	return
+}
+
+// Parse a broadcast xml. Unknown meta rows are ignored, DC.format.duration is
+// only used if DC.format.timeend is missing.
+//
+// Of the Station only the Identifier (from DC.identifier) is set.
+func Read(xmlFile io.Reader) (bc scrape.Broadcast, err error) {
+	x := broadcastXml{}
+	if err = xml.NewDecoder(xmlFile).Decode(&x); nil != err {
+		return
+	}
+	ps := func(s string) *string {
+		return &s
+	}
+	var duration *time.Duration
+	for _, row := range x.Meta {
+		switch row.Name {
+		case "DC.identifier":
+			bc.Station.Identifier = strings.SplitN(row.Content, "/", 2)[0]
+		case "DC.language":
+			bc.Language = ps(row.Content)
+		case "DC.title":
+			bc.Title = row.Content
+		case "DC.title.series":
+			bc.TitleSeries = ps(row.Content)
+		case "DC.title.episode":
+			bc.TitleEpisode = ps(row.Content)
+		case "DC.subject":
+			if bc.Subject, err = url.Parse(row.Content); nil != err {
+				return
+			}
+		case "DC.format.timestart":
+			if bc.Time, err = time.Parse(time.RFC3339, row.Content); nil != err {
+				return
+			}
+		case "DC.format.timeend":
+			t, e := time.Parse(time.RFC3339, row.Content)
+			if nil != e {
+				return bc, e
+			}
+			bc.DtEnd = &t
+		case "DC.format.duration":
+			i, e := strconv.Atoi(row.Content)
+			if nil != e {
+				return bc, e
+			}
+			d := time.Duration(i) * time.Second
+			duration = &d
+		case "DC.image":
+			if bc.Image, err = url.Parse(row.Content); nil != err {
+				return
+			}
+		case "DC.description":
+			bc.Description = ps(row.Content)
+		case "DC.author":
+			bc.Author = ps(row.Content)
+		case "DC.publisher":
+			bc.Publisher = ps(row.Content)
+		case "DC.creator":
+			bc.Creator = ps(row.Content)
+		case "DC.copyright":
+			bc.Copyright = ps(row.Content)
+		case "DC.source":
+			u, e := url.Parse(row.Content)
+			if nil != e {
+				return bc, e
+			}
+			bc.Source = *u
+		default:
+			// DC.scheme and whatever else may come.
+		}
+	}
+	if "" == bc.Station.Identifier {
+		return bc, errors.New("missing DC.identifier")
+	}
+	if bc.Time.IsZero() {
+		return bc, errors.New("missing DC.format.timestart")
+	}
+	if nil == bc.DtEnd && nil != duration {
+		t := bc.Time.Add(*duration)
+		bc.DtEnd = &t
+	}
+	if nil == bc.Language && "" != x.Language {
+		bc.Language = ps(x.Language)
+	}
+	return
+}
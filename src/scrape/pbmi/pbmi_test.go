@@ -0,0 +1,147 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape/pbmi"
+//
+package pbmi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+func TestReadFile(t *testing.T) {
+	_, err := ReadFile("")
+	assert.Equal(t, "open : no such file or directory", err.Error(), "soso")
+
+	bc, err := ReadFile("testdata/b2-2016-08-25-1805.xml")
+	assert.Nil(t, err, "soso")
+	assert.Equal(t, "Bayern 2-radioMusik", bc.Title, "aha")
+	assert.Equal(t, "b2", bc.Station.Identifier, "aha")
+	assert.Equal(t, "b2/2016/08/25/1805 Bayern 2-radioMusik", Identifier(bc), "aha")
+	assert.Equal(t, "de", *bc.Language, "aha")
+	assert.Equal(t, "anspruchsvoll - entspannt - weltoffen", *bc.TitleEpisode, "aha")
+	assert.Nil(t, bc.TitleSeries, "aha")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/index.html", bc.Subject.String(), "aha")
+	assert.Equal(t, "2016-08-25T18:05:00+02:00", bc.Time.Format(time.RFC3339), "aha")
+	assert.Equal(t, "2016-08-25T18:30:00+02:00", bc.DtEnd.Format(time.RFC3339), "aha")
+	assert.Equal(t, 1500*time.Second, bc.DtEnd.Sub(bc.Time), "aha")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/rebekka-bakken-102~_v-img__16__9__m_-4423061158a17f4152aef84861ed0243214ae6e7.jpg?version=64958", bc.Image.String(), "aha")
+	assert.Equal(t, "anspruchsvoll - entspannt - weltoffen\nMit Riegler Hias feat. D'Hundskrippln, Rebekka Bakken, Randy Newman und vielen mehr\nModeration: Thomas Mehringer", *bc.Description, "aha")
+	assert.Equal(t, "Bayerischer Rundfunk", *bc.Author, "aha")
+	assert.Nil(t, bc.Publisher, "aha")
+	assert.Nil(t, bc.Creator, "aha")
+	assert.Nil(t, bc.Copyright, "aha")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-772436.html", bc.Source.String(), "aha")
+	assert.NotNil(t, bc.Modified, "aha")
+}
+
+func TestReadWriteRoundTrip(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/b2-2016-08-25-1805.xml")
+	assert.Nil(t, err, "ouch")
+
+	bc, err := Read(bytes.NewReader(expected))
+	assert.Nil(t, err, "ouch")
+
+	var buf bytes.Buffer
+	err = Write(&buf, bc)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, string(expected), buf.String(), "ouch")
+}
+
+func TestReadUnknownMeta(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<broadcast xml:lang="en" xmlns="../../../../../assets/2013/radio-pi.rdf">
+    <meta content='dlf/2015/10/25/0205 Radionacht' name='DC.identifier'/>
+    <meta content='Radionacht' name='DC.title'/>
+    <meta content='2015-10-25T02:05:00+01:00' name='DC.format.timestart'/>
+    <meta content='14100' name='DC.format.duration'/>
+    <meta content='whatever' name='DC.foo'/>
+    <meta content='Deutschlandradio' name='DC.copyright'/>
+    <meta content='Deutschlandfunk' name='DC.creator'/>
+    <meta content='http://www.deutschlandfunk.de/programmvorschau.281.de.html' name='DC.source'/>
+</broadcast>`
+	bc, err := Read(strings.NewReader(src))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "dlf", bc.Station.Identifier, "ouch")
+	assert.Equal(t, "Radionacht", bc.Title, "ouch")
+	assert.Equal(t, "en", *bc.Language, "xml:lang as fallback")
+	assert.Equal(t, "2015-10-25T06:00:00+01:00", bc.DtEnd.Format(time.RFC3339), "from duration")
+	assert.Equal(t, "Deutschlandradio", *bc.Copyright, "ouch")
+	assert.Equal(t, "Deutschlandfunk", *bc.Creator, "ouch")
+	assert.Nil(t, bc.Modified, "ouch")
+}
+
+func TestReadMissingIdentifier(t *testing.T) {
+	src := `<broadcast xmlns="../../../../../assets/2013/radio-pi.rdf">
+    <meta content='2015-10-25T02:05:00+01:00' name='DC.format.timestart'/>
+</broadcast>`
+	_, err := Read(strings.NewReader(src))
+	assert.Equal(t, "missing DC.identifier", err.Error(), "ouch")
+}
+
+func TestXmlEncoding(t *testing.T) {
+	x := broadcastXml{
+		Language: "de",
+		Meta: []xmlEntry{
+			{
+				Name:    "Foo",
+				Content: "bar",
+			},
+		},
+	}
+	buf := new(bytes.Buffer)
+	err := xml.NewEncoder(buf).Encode(x)
+	assert.Nil(t, err, "jaja")
+	assert.Equal(t, "<broadcast xmlns=\"../../../../../assets/2013/radio-pi.rdf\" xml:lang=\"de\"><meta name=\"Foo\" content=\"bar\"></meta></broadcast>", buf.String(), "echt?")
+}
+
+func TestToFilename(t *testing.T) {
+	assert.Equal(t, "Jazz - Blues-Rock  live", ToFilename("Jazz – Blues/Rock\t\nlive"), "ouch")
+}
+
+func TestIdentifier(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	bc := scrape.Broadcast{}
+	bc.Station = scrape.Station{Identifier: "b2", TimeZone: tz}
+	bc.Time = time.Date(2016, time.August, 25, 18, 5, 0, 0, tz)
+	bc.Title = "Bayern 2-radioMusik"
+	assert.Equal(t, "b2/2016/08/25/1805 Bayern 2-radioMusik", Identifier(bc), "ouch")
+	// the station's time zone decides about the path, not the zone of the time
+	bc.Time = bc.Time.UTC()
+	assert.Equal(t, "b2/2016/08/25/1805 Bayern 2-radioMusik", Identifier(bc), "ouch")
+}
+
+func TestWriteMissingDtEnd(t *testing.T) {
+	bc, err := ReadFile("testdata/b2-2016-08-25-1805.xml")
+	assert.Nil(t, err, "ouch")
+	bc.DtEnd = nil
+	err = Write(new(bytes.Buffer), bc)
+	assert.NotNil(t, err, "ouch")
+}
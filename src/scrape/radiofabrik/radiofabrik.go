@@ -20,10 +20,10 @@
 package radiofabrik // import "purl.mro.name/recorder/radio/scrape/radiofabrik"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -50,6 +50,11 @@ func Station(identifier string) *station {
 	return nil
 }
 
+func init() {
+	r.Register(r.Station(*Station("radiofabrik")), func(st r.Station) r.Scraper { s := station(st); return &s })
+	r.RegisterLevel("radiofabrik", "day", func(tu r.TimeURL) r.Scraper { return timeURL(tu) })
+}
+
 /// Stringer
 func (s *station) String() string {
 	return fmt.Sprintf("Station '%s'", s.Name)
@@ -60,10 +65,13 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // Synthesise calItemRangeURLs for incremental scraping and queue them up
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	now := time.Now()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	now := r.Now()
 	for _, t0 := range r.IncrementalNows(now) {
-		day, _ := s.dayURLForDate(t0)
+		day, err := s.dayURLForDate(t0)
+		if nil != err {
+			return nil, nil, err
+		}
 		jobs = append(jobs, r.Scraper(*day))
 	}
 	return
@@ -73,9 +81,13 @@ func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error
 // http://www.deutschlandfunk.de/programmvorschau.281.de.html?drbm:date=19.11.2015
 
 func (s *station) dayURLForDate(day time.Time) (ret *timeURL, err error) {
+	u, err := url.Parse(s.ProgramURL.String() + day.Format("?foo=bar&si_day=02&si_month=01&si_year=2006"))
+	if nil != err {
+		return
+	}
 	r := timeURL(r.TimeURL{
 		Time:    time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.TimeZone),
-		Source:  *r.MustParseURL(s.ProgramURL.String() + day.Format("?foo=bar&si_day=02&si_month=01&si_year=2006")),
+		Source:  *u,
 		Station: r.Station(*s),
 	})
 	ret = &r
@@ -93,8 +105,8 @@ func (day timeURL) Matches(nows []time.Time) (ok bool) {
 }
 
 // Scrape broadcasts from a day page.
-func (day timeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := day.parseBroadcastsFromURL()
+func (day timeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := day.parseBroadcastsFromURL(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			results = append(results, bc)
@@ -129,8 +141,17 @@ func (day *timeURL) parseBroadcastsFromNode(root *html.Node) (ret []*r.Broadcast
 		{
 			hhmm := scrape.Text(at)
 			// fmt.Fprintf(os.Stderr, "  a_id=%s\n", a_id)
-			hour := r.MustParseInt(hhmm[0:2])
-			minute := r.MustParseInt(hhmm[3:5])
+			if len(hhmm) < 5 {
+				return nil, r.NewParseError(day.Source, at, errors.New("Couldn't parse hh:mm"))
+			}
+			hour, err := r.ParseInt(day.Source, hhmm[0:2])
+			if nil != err {
+				return nil, err
+			}
+			minute, err := r.ParseInt(day.Source, hhmm[3:5])
+			if nil != err {
+				return nil, err
+			}
 			if 24 < hour || 60 < minute {
 				continue
 			}
@@ -171,30 +192,31 @@ func (day *timeURL) parseBroadcastsFromNode(root *html.Node) (ret []*r.Broadcast
 	if index > 0 {
 		midnight := time.Date(day.Year(), day.Month(), day.Day(), 24, 0, 0, 0, day.TimeZone)
 		ret[index-1].DtEnd = &midnight
+		ret[index-1].DtEndGuessed = true // until the next day tells, see r.Stitch
 	}
 	return
 }
 
-func (day *timeURL) parseBroadcastsFromReader(read io.Reader) (ret []*r.Broadcast, err error) {
+func (day *timeURL) parseBroadcastsFromReader(read io.Reader, cr0 *r.CountingReader) (ret []*r.Broadcast, err error) {
 	cr := r.NewCountingReader(read)
 	root, err := html.Parse(cr)
-	r.ReportLoad("🐦", cr, cr, day.Source)
+	r.ReportLoad("🐦", cr0, cr, day.Source)
 	if nil != err {
 		return
 	}
 	return day.parseBroadcastsFromNode(root)
 }
 
-func (day *timeURL) parseBroadcastsFromURL() (ret []*r.Broadcast, err error) {
+func (day *timeURL) parseBroadcastsFromURL(ctx context.Context) (ret []*r.Broadcast, err error) {
 	s := day.Source.String()
 	m, err := url.ParseQuery(s)
 	if nil != err {
 		return
 	}
-	resp, err := http.PostForm(s, m)
-	if nil != err {
-		return
+	bo, cr, err := r.HttpPostForm(ctx, day.Source, m)
+	if nil == bo {
+		return nil, err
 	}
-	defer resp.Body.Close()
-	return day.parseBroadcastsFromReader(resp.Body)
+	defer bo.Close()
+	return day.parseBroadcastsFromReader(bo, cr)
 }
@@ -45,6 +45,20 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", s.TimeZone.String(), "ouch: TimeZone")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("radiofabrik"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 56, len(bcs), "today twice, for now and now + 12h")
+	first := bcs[0]
+	last := bcs[27]
+	assert.Equal(t, "radiofabrik", first.Station.Identifier, "ouch")
+	assert.Equal(t, "My Favourite Music. With David Hubble", first.Title, "ouch")
+	assert.Equal(t, "2016-03-05T01:00:00+01:00", first.Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "Night Shift Radio - Musik - damit die Glotze ausbleibt", last.Title, "ouch")
+	assert.Equal(t, "2016-03-06T00:00:00+01:00", last.DtEnd.Format(time.RFC3339), "ouch")
+}
+
 func TestDayURLForDate(t *testing.T) {
 	s := Station("radiofabrik")
 	u, err := s.dayURLForDate(time.Date(2016, 3, 9, 5, 0, 0, 0, s.TimeZone))
@@ -70,7 +84,7 @@ func TestParseBroadcasts(t *testing.T) {
 		Station: r.Station(*s),
 	})
 
-	bcs, err := u.parseBroadcastsFromReader(f)
+	bcs, err := u.parseBroadcastsFromReader(f, nil)
 	assert.NotNil(t, bcs, "ouch")
 	assert.Nil(t, err, "ouch")
 	assert.Equal(t, 28, len(bcs), "ouch")
@@ -0,0 +1,117 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Station packages register their stations here, see Register.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Create the root Scraper of a station, see LoadStations.
+type Factory func(station Station) Scraper
+
+type registration struct {
+	station Station
+	factory Factory
+	levels  map[string]LevelFactory // see RegisterLevel
+}
+
+var registry = struct {
+	sync.Mutex
+	m map[string]registration
+}{m: make(map[string]registration)}
+
+// Make a station known, typically from the init() of a station package.
+// Panics if the identifier is taken already.
+func Register(station Station, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if "" == station.Identifier {
+		panic("How can the identifier miss?")
+	}
+	if _, ok := registry.m[station.Identifier]; ok {
+		panic("station registered twice: " + station.Identifier)
+	}
+	registry.m[station.Identifier] = registration{station: station, factory: factory}
+}
+
+type sourceReg struct {
+	factory  Factory
+	required []string
+}
+
+var sources = struct {
+	sync.Mutex
+	m map[string]sourceReg
+}{m: make(map[string]sourceReg)}
+
+// Offer a generic scraper to stations configured by data only, i.e. a
+// station.cfg saying scrape_source = 'name', see LoadStations. The Factory
+// finds its settings in Station.Params, LoadStations refuses a station.cfg
+// missing one of the required ones.
+func RegisterSource(name string, factory Factory, required ...string) {
+	sources.Lock()
+	defer sources.Unlock()
+	if _, ok := sources.m[name]; ok {
+		panic("source registered twice: " + name)
+	}
+	sources.m[name] = sourceReg{factory: factory, required: required}
+}
+
+func source(name string) (Factory, bool) {
+	src, ok := sourceFor(name)
+	return src.factory, ok
+}
+
+func sourceFor(name string) (sourceReg, bool) {
+	sources.Lock()
+	defer sources.Unlock()
+	src, ok := sources.m[name]
+	return src, ok
+}
+
+// All registered stations, sorted by Identifier.
+func Stations() (ret []Station) {
+	registry.Lock()
+	defer registry.Unlock()
+	ret = make([]Station, 0, len(registry.m))
+	for _, reg := range registry.m {
+		ret = append(ret, reg.station)
+	}
+	sort.Slice(ret, func(a, b int) bool { return ret[a].Identifier < ret[b].Identifier })
+	return
+}
+
+// The root Scraper of a registered station. Applies the station's Limit.
+func NewScraper(identifier string) (Scraper, error) {
+	registry.Lock()
+	reg, ok := registry.m[identifier]
+	registry.Unlock()
+	if !ok {
+		return nil, errors.New("unknown station: " + identifier)
+	}
+	reg.station.RegisterLimit()
+	return reg.factory(reg.station), nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopScraper string
+
+func (n nopScraper) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	return
+}
+
+func (n nopScraper) Matches(nows []time.Time) bool { return true }
+
+func TestRegistry(t *testing.T) {
+	factory := func(st Station) Scraper { return nopScraper(st.Identifier) }
+	Register(Station{Identifier: "test-z", Name: "Z"}, factory)
+	Register(Station{Identifier: "test-a", Name: "A", CloseDown: "05:00"}, factory)
+
+	var ids []string
+	for _, s := range Stations() {
+		ids = append(ids, s.Identifier)
+	}
+	assert.Equal(t, []string{"test-a", "test-z"}, ids, "sorted")
+	assert.Equal(t, "05:00", Stations()[0].CloseDown, "ouch")
+
+	s, err := NewScraper("test-z")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, nopScraper("test-z"), s, "ouch")
+
+	_, err = NewScraper("nope")
+	assert.Equal(t, "unknown station: nope", err.Error(), "ouch")
+
+	assert.Panics(t, func() { Register(Station{Identifier: "test-a"}, factory) }, "no duplicates")
+	assert.Panics(t, func() { Register(Station{}, factory) }, "no empty identifier")
+}
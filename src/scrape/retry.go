@@ -0,0 +1,105 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Retry transient failures (timeouts, 5xx, connection reset) with
+// exponential backoff and jitter, give up on permanent ones right away.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Worth trying again? Parse errors, 404 and the like are not.
+func IsTransient(err error) bool {
+	for nil != err {
+		switch e := err.(type) {
+		case *StatusError:
+			return 500 <= e.StatusCode || http.StatusRequestTimeout == e.StatusCode || http.StatusTooManyRequests == e.StatusCode
+		case *FetchError:
+			err = e.Err
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			if e.Timeout() {
+				return true
+			}
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case syscall.Errno:
+			return syscall.ECONNRESET == e || syscall.ECONNREFUSED == e || syscall.ECONNABORTED == e || syscall.ETIMEDOUT == e || syscall.EPIPE == e
+		case net.Error:
+			return e.Timeout() || e.Temporary()
+		default:
+			// a plain io.EOF is how a parser sees an empty or truncated body, no use in asking again
+			return context.DeadlineExceeded == err || io.ErrUnexpectedEOF == err
+		}
+	}
+	return false
+}
+
+type Backoff struct {
+	Retries int           // attempts after the first one
+	Initial time.Duration // delay before the first retry, doubled for each further one
+	Max     time.Duration // upper bound for the delay
+}
+
+var DefaultBackoff = Backoff{Retries: 3, Initial: 2 * time.Second, Max: 30 * time.Second}
+
+// Delay before retry number attempt (0-based), randomised within [d/2,d].
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Initial
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if 0 < b.Max && b.Max < d {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Call s.Scrape and retry transient errors until ctx is done. Returns the
+// outcome of the last attempt and the number of retries.
+func (b Backoff) Scrape(ctx context.Context, s Scraper) (jobs []Scraper, results []Broadcaster, retries int, err error) {
+	for {
+		if jobs, results, err = s.Scrape(ctx); nil == err || !IsTransient(err) || retries >= b.Retries {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.Delay(retries)):
+		}
+		retries++
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fetches a url and returns no broadcasts
+type fetchScraper struct {
+	url string
+}
+
+func (f fetchScraper) Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error) {
+	bo, _, err := HttpGetBody(ctx, *MustParseURL(f.url))
+	if nil == bo {
+		return
+	}
+	defer bo.Close()
+	_, err = ioutil.ReadAll(bo)
+	return
+}
+
+func (f fetchScraper) Matches(nows []time.Time) bool { return true }
+
+// fails the first n requests with status
+func failingServer(n int32, status int) (*httptest.Server, *int32) {
+	var count int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= n {
+			http.Error(w, "nope", status)
+			return
+		}
+		w.Write([]byte("<html/>"))
+	})), &count
+}
+
+var fastBackoff = Backoff{Retries: 3, Initial: time.Millisecond, Max: 4 * time.Millisecond}
+
+func TestRetryTransient(t *testing.T) {
+	srv, count := failingServer(2, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	_, _, retries, err := fastBackoff.Scrape(context.Background(), fetchScraper{srv.URL})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, retries, "ouch")
+	assert.Equal(t, int32(3), *count, "ouch")
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	srv, count := failingServer(10, http.StatusBadGateway)
+	defer srv.Close()
+
+	_, _, retries, err := fastBackoff.Scrape(context.Background(), fetchScraper{srv.URL})
+	assert.Equal(t, http.StatusBadGateway, err.(*StatusError).StatusCode, "ouch")
+	assert.Equal(t, 3, retries, "ouch")
+	assert.Equal(t, int32(4), *count, "ouch")
+}
+
+func TestRetryPermanent(t *testing.T) {
+	srv, count := failingServer(10, http.StatusNotFound)
+	defer srv.Close()
+
+	_, _, retries, err := fastBackoff.Scrape(context.Background(), fetchScraper{srv.URL})
+	assert.Equal(t, http.StatusNotFound, err.(*StatusError).StatusCode, "ouch")
+	assert.Equal(t, 0, retries, "ouch")
+	assert.Equal(t, int32(1), *count, "ouch")
+}
+
+func TestRetryCancel(t *testing.T) {
+	srv, count := failingServer(10, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	b := Backoff{Retries: 10, Initial: time.Hour, Max: time.Hour}
+	t0 := time.Now()
+	_, _, retries, err := b.Scrape(ctx, fetchScraper{srv.URL})
+	assert.NotNil(t, err, "ouch")
+	assert.Equal(t, 0, retries, "ouch")
+	assert.Equal(t, int32(1), *count, "ouch")
+	assert.True(t, time.Since(t0) < time.Second, "doesn't sleep past the deadline")
+}
+
+func TestIsTransient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u := srv.URL
+	srv.Close()
+	_, _, err := HttpGetBody(context.Background(), *MustParseURL(u))
+	assert.True(t, IsTransient(err), "connection refused: "+err.Error())
+
+	assert.True(t, IsTransient(&StatusError{StatusCode: 500}), "ouch")
+	assert.True(t, IsTransient(&StatusError{StatusCode: 429}), "ouch")
+	assert.False(t, IsTransient(&StatusError{StatusCode: 404}), "ouch")
+	assert.False(t, IsTransient(errors.New("Couldn't parse <a>")), "ouch")
+	assert.True(t, IsTransient(&FetchError{Err: io.ErrUnexpectedEOF}), "connection dropped mid-body")
+	assert.False(t, IsTransient(io.EOF), "e.g. an empty body")
+	assert.False(t, IsTransient(nil), "ouch")
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Retries: 5, Initial: time.Second, Max: 5 * time.Second}
+	for i, max := range []time.Duration{1, 2, 4, 5, 5} {
+		d := b.Delay(i)
+		assert.True(t, max*time.Second/2 <= d && d <= max*time.Second, d.String())
+	}
+}
@@ -27,6 +27,7 @@
 package scrape
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/url"
@@ -38,7 +39,8 @@ import (
 
 // Something that can be scraped.
 type Scraper interface {
-	Scrape() (jobs []Scraper, results []Broadcaster, err error)
+	// ctx carries cancellation and the run deadline, pass it on to HttpGetBody.
+	Scrape(ctx context.Context) (jobs []Scraper, results []Broadcaster, err error)
 
 	// is (re-)scraping due for this entity?
 	Matches(nows []time.Time) (ok bool)
@@ -48,11 +50,27 @@ type Scraper interface {
 type Broadcaster interface {
 	// Do as the name indicates.
 	WriteAsLuaTable(w io.Writer) (err error)
+
+	// One JSON object per line (JSON Lines).
+	WriteAsJSON(w io.Writer) (err error)
+}
+
+// The Broadcast behind b. Some stations emit values, some pointers.
+func AsBroadcast(b Broadcaster) (ret Broadcast, ok bool) {
+	switch bc := b.(type) {
+	case Broadcast:
+		return bc, true
+	case *Broadcast:
+		if nil != bc {
+			return *bc, true
+		}
+	}
+	return
 }
 
 func (b Broadcast) WriteAsLuaTable(w io.Writer) (err error) {
-	if "" == b.Station.Identifier {
-		panic("How can the identifier miss?")
+	if err = b.Validate(); nil != err {
+		return
 	}
 	// https://github.com/mro/radio-pi/blob/master/htdocs/app/recorder.rb#L188
 	fmt.Fprintf(w, "\n-- comma separated lua tables, one per broadcast:\n{\n")
@@ -89,9 +107,6 @@ func (b Broadcast) WriteAsLuaTable(w io.Writer) (err error) {
 	if nil != b.DtEnd {
 		ft("DC_format_timeend", *b.DtEnd)
 		dt := b.DtEnd.Sub(b.Time) / time.Second
-		if dt < 0 {
-			panic("dt < 0 for " + b.Source.String())
-		}
 		f("DC_format_duration", strconv.FormatInt(int64(dt), 10))
 	}
 	fpu("DC_image", b.Image)
@@ -113,6 +128,8 @@ func (b Broadcast) WriteAsLuaTable(w io.Writer) (err error) {
 type CountingReader struct {
 	reader     io.Reader
 	TotalBytes int64
+	Cached     bool // read from the disk cache, not the network
+	stats      *Stats
 }
 
 func NewCountingReader(r io.Reader) *CountingReader {
@@ -126,17 +143,26 @@ func (cr *CountingReader) Read(p []byte) (n int, err error) {
 }
 
 func ReportLoad(marker string, cr0 *CountingReader, cr *CountingReader, url url.URL) {
-	loaded := int64(0)
+	verb, loaded := "loaded", int64(0)
 	if nil != cr0 {
 		loaded = cr0.TotalBytes
+		if cr0.Cached {
+			verb = "cached"
+		}
+		if nil != cr0.stats {
+			cr0.stats.page(cr0, cr)
+		}
 	}
-	fmt.Fprintf(os.Stderr, "loaded %d B parsed %d B %s %s\n", loaded, cr.TotalBytes, marker, url.String())
+	fmt.Fprintf(os.Stderr, "%s %d B parsed %d B %s %s\n", verb, loaded, cr.TotalBytes, marker, url.String())
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////
 /// Some Helpers that may be useful but are totally optional.
 //////////////////////////////////////////////////////////////////////////////////////////
 
+// The clock of the scrapers, replaceable for tests, see Fixtures.
+var Now = time.Now
+
 // Instances of time.Time when incremental scrapes are due.
 func IncrementalNows(now time.Time) (ret []time.Time) {
 	src := []time.Duration{0, 12, 3 * 24, 7 * 24, 7 * 7 * 24}
@@ -155,6 +181,35 @@ type Station struct {
 	CloseDown  string
 	ProgramURL *url.URL
 	TimeZone   *time.Location
+	Limit      *HostLimit // politeness towards the ProgramURL host, nil: DefaultHostLimit
+	Homepage   *url.URL
+	Logo       *url.URL
+	Params     map[string]string // all of station.cfg, for configured scrapers, see RegisterSource
+}
+
+// Apply Limit to the ProgramURL host, see SetHostLimit.
+func (s Station) RegisterLimit() {
+	if nil != s.Limit && nil != s.ProgramURL {
+		SetHostLimit(s.ProgramURL.Host, *s.Limit)
+	}
+}
+
+// When the station's day containing t starts, i.e. CloseDown that day in
+// TimeZone, or the day before if t is earlier. t itself if unknown.
+func (s Station) DayStart(t time.Time) time.Time {
+	if "" == s.CloseDown || nil == s.TimeZone {
+		return t
+	}
+	t = t.In(s.TimeZone)
+	ret, err := time.ParseInLocation("2006-01-02 15:04", t.Format("2006-01-02")+" "+s.CloseDown, s.TimeZone)
+	if nil != err {
+		return t
+	}
+	if t.Before(ret) {
+		y, m, d := t.Date()
+		ret, _ = time.ParseInLocation("2006-01-02 15:04", time.Date(y, m, d-1, 12, 0, 0, 0, s.TimeZone).Format("2006-01-02")+" "+s.CloseDown, s.TimeZone)
+	}
+	return ret
 }
 
 // Basic data about a url connected with a time.Time.
@@ -189,6 +244,7 @@ type Broadcast struct {
 	TitleSeries  *string
 	TitleEpisode *string
 	DtEnd        *time.Time
+	DtEndGuessed bool // DtEnd is a stand-in, e.g. midnight for the last of a day, see Stitch
 	Modified     *time.Time
 	Subject      *url.URL
 	Image        *url.URL
@@ -200,6 +256,7 @@ type Broadcast struct {
 	Copyright    *string
 }
 
+// For literals only, page content goes through url.Parse and NewParseError.
 func MustParseURL(s string) *url.URL {
 	ret, err := url.Parse(s)
 	if nil != err {
@@ -208,6 +265,7 @@ func MustParseURL(s string) *url.URL {
 	return ret
 }
 
+// For literals only, page content goes through ParseInt.
 func MustParseInt(s string) int {
 	ret, err := strconv.ParseInt(s, 10, 12)
 	if nil != err {
@@ -0,0 +1,180 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// A small subset of CSS selectors, see Select.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+)
+
+// One of div.descr, #main, a[href], [data-x=y], and what it must hang below.
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   [][2]string // key, value; value "*" matches any
+	child   bool        // the parent, not just an ancestor, must match up
+	up      *compound
+}
+
+func (c *compound) matchesSelf(n *html.Node) bool {
+	if html.ElementNode != n.Type || ("" != c.tag && c.tag != n.Data) {
+		return false
+	}
+	if "" != c.id && c.id != scrape.Attr(n, "id") {
+		return false
+	}
+	classes := strings.Fields(scrape.Attr(n, "class"))
+	for _, cl := range c.classes {
+		found := false
+		for _, have := range classes {
+			found = found || cl == have
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, kv := range c.attrs {
+		ok := false
+		for _, a := range n.Attr {
+			ok = ok || (kv[0] == a.Key && ("*" == kv[1] || kv[1] == a.Val))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compound) matches(n *html.Node) bool {
+	if !c.matchesSelf(n) {
+		return false
+	}
+	if nil == c.up {
+		return true
+	}
+	for p := n.Parent; nil != p; p = p.Parent {
+		if c.up.matches(p) {
+			return true
+		}
+		if c.up.child {
+			break
+		}
+	}
+	return false
+}
+
+func parseCompound(s string) (ret *compound, err error) {
+	ret = &compound{}
+	for i := 0; i < len(s); {
+		j := len(s)
+		if '[' == s[i] {
+			if k := strings.IndexByte(s[i:], ']'); 0 <= k {
+				j = i + k + 1
+			} else {
+				return nil, errors.New("unbalanced [ in '" + s + "'")
+			}
+		} else if k := strings.IndexAny(s[i+1:], ".#["); 0 <= k {
+			j = i + 1 + k
+		}
+		part := s[i:j]
+		if "" == strings.Trim(part, ".#[]") {
+			return nil, errors.New("empty name in '" + s + "'")
+		}
+		switch part[0] {
+		case '.':
+			ret.classes = append(ret.classes, part[1:])
+		case '#':
+			ret.id = part[1:]
+		case '[':
+			kv := strings.SplitN(part[1:len(part)-1], "=", 2)
+			if 1 == len(kv) {
+				kv = append(kv, "*")
+			}
+			ret.attrs = append(ret.attrs, [2]string{kv[0], strings.Trim(kv[1], `"`)})
+		default:
+			if 0 != i {
+				return nil, errors.New("misplaced tag in '" + s + "'")
+			}
+			if "*" != part {
+				ret.tag = strings.ToLower(part)
+			}
+		}
+		i = j
+	}
+	return
+}
+
+// A matcher for selectors like "div.item > div.descr a, p[class]": tags,
+// .class, #id, [attr] and [attr=value] combined by descendant ' ', child '>'
+// and alternatives ','. The matcher ignores where FindAll started, so "li a"
+// also matches below a 'li' outside of it.
+func Select(selector string) (scrape.Matcher, error) {
+	alts := []*compound{}
+	for _, alt := range strings.Split(selector, ",") {
+		var cur *compound
+		child := false
+		for _, tok := range strings.Fields(strings.Replace(alt, ">", " > ", -1)) {
+			if ">" == tok {
+				if nil == cur || child {
+					return nil, errors.New("misplaced > in '" + selector + "'")
+				}
+				child = true
+				continue
+			}
+			c, err := parseCompound(tok)
+			if nil != err {
+				return nil, err
+			}
+			if nil != cur {
+				cur.child = child
+			}
+			c.up, cur, child = cur, c, false
+		}
+		if nil == cur || child {
+			return nil, errors.New("incomplete selector '" + selector + "'")
+		}
+		alts = append(alts, cur)
+	}
+	return func(n *html.Node) bool {
+		for _, c := range alts {
+			if c.matches(n) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// For literals only, like MustParseURL.
+func MustSelect(selector string) scrape.Matcher {
+	ret, err := Select(selector)
+	if nil != err {
+		panic(err)
+	}
+	return ret
+}
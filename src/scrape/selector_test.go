@@ -0,0 +1,71 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+)
+
+func TestSelect(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<body>
+<div id="main" class="list day">
+  <div class="item bg"><div class="time">00:00</div><div class="descr"><a>A</a></div></div>
+  <div class="item"><div class="time">01:00</div><div class="descr"><p><a href="/b" data-x="y">B</a></p></div></div>
+</div>
+<div class="item"><a href="/c">C</a></div>
+</body>`))
+	assert.Nil(t, err, "ouch")
+	texts := func(selector string) string {
+		ret := []string{}
+		for _, n := range scrape.FindAll(root, MustSelect(selector)) {
+			ret = append(ret, scrape.Text(n))
+		}
+		return strings.Join(ret, "|")
+	}
+	assert.Equal(t, "A|B|C", texts("a"), "ouch")
+	assert.Equal(t, "00:00|01:00", texts("div.time"), "ouch")
+	assert.Equal(t, "00:00|01:00", texts(".time"), "ouch")
+	assert.Equal(t, "A", texts("div.descr > a"), "child only")
+	assert.Equal(t, "A|B", texts("div.descr a"), "descendant")
+	assert.Equal(t, "A|B", texts("#main a"), "ouch")
+	assert.Equal(t, "A|B", texts("div.list.day div.item a"), "ouch")
+	assert.Equal(t, "00:00 A", texts("div.item.bg"), "ouch")
+	assert.Equal(t, "B|C", texts("a[href]"), "ouch")
+	assert.Equal(t, "C", texts("a[href=/c]"), "ouch")
+	assert.Equal(t, "B", texts(`[data-x="y"]`), "ouch")
+	assert.Equal(t, "A|C", texts("div.descr>a, body > div > a"), "alternatives")
+	assert.Equal(t, "A|B", texts("* > div.descr > *"), "ouch")
+	assert.Equal(t, "", texts("span"), "ouch")
+
+	for _, s := range []string{"", "a >", "> a", "a > > b", "a,", "div.", "a[href", "div#", "div..x", "div a#"} {
+		_, err := Select(s)
+		assert.NotNil(t, err, s)
+	}
+}
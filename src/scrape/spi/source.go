@@ -0,0 +1,126 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package spi
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// A station configured by data only
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// htdocs/stations/<id>/app/station.cfg like
+//
+//	{
+//		title = 'Bayern 2',
+//		program_url = 'https://www.br.de/radio/bayern2/programmkalender/',
+//		scrape_source = 'spi',
+//		scrape_url = 'https://epg.example/radiodns/spi/3.1/',
+//		spi_bearer = 'dab:de0.10b1.d3e1.0', -- see the SI.xml bearers
+//		day_start = '0500',
+//		timezone = 'Europe/Berlin',
+//	}
+func init() {
+	scrape.RegisterSource("spi", func(st scrape.Station) scrape.Scraper { s := station(st); return &s }, "spi_bearer")
+}
+
+type station scrape.Station
+
+func (s *station) String() string {
+	return "spi " + s.Identifier + " " + s.ProgramURL.String()
+}
+
+func (s *station) Matches(nows []time.Time) (ok bool) {
+	return true
+}
+
+// Synthesise the <date>_PI.xml days for incremental scraping and queue them up
+func (s *station) Scrape(ctx context.Context) (jobs []scrape.Scraper, results []scrape.Broadcaster, err error) {
+	for _, t0 := range scrape.IncrementalNows(scrape.Now()) {
+		day, err := s.dayURLForDate(t0)
+		if nil != err {
+			return nil, nil, err
+		}
+		jobs = append(jobs, day)
+	}
+	return
+}
+
+// dab:de0.10b1.d3e1.0 becomes dab/de0/10b1/d3e1/0
+func bearerPath(bearer string) string {
+	return strings.NewReplacer(":", "/", ".", "/").Replace(bearer)
+}
+
+// <scrape_url>/dab/de0/10b1/d3e1/0/20151114_PI.xml
+func (s *station) dayURLForDate(day time.Time) (*timeURL, error) {
+	day = day.In(s.TimeZone)
+	base := strings.TrimSuffix(s.ProgramURL.String(), "/")
+	u, err := url.Parse(base + "/" + bearerPath(s.Params["spi_bearer"]) + day.Format("/20060102_PI.xml"))
+	if nil != err {
+		return nil, err
+	}
+	return &timeURL{
+		Time:    time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.TimeZone),
+		Source:  *u,
+		Station: scrape.Station(*s),
+	}, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////
+/// Just wrap TimeURL into a distinct, local type - a Scraper, naturally
+type timeURL scrape.TimeURL
+
+func (day *timeURL) Matches(nows []time.Time) (ok bool) {
+	return true
+}
+
+// Scrape all programmes of a <date>_PI.xml
+func (day *timeURL) Scrape(ctx context.Context) (jobs []scrape.Scraper, results []scrape.Broadcaster, err error) {
+	bo, cr0, err := scrape.HttpGetBody(ctx, day.Source)
+	if nil == bo {
+		return nil, nil, err
+	}
+	defer bo.Close()
+	cr := scrape.NewCountingReader(bo)
+	epg, err := ReadEPG(cr)
+	scrape.ReportLoad("🐦", cr0, cr, day.Source)
+	if nil != err {
+		return nil, nil, &scrape.ParseError{URL: day.Source, Err: err}
+	}
+	groups := epg.Groups()
+	for _, sch := range epg.Schedules {
+		for _, p := range sch.Programmes {
+			bcs, err := p.Broadcasts(day.Station, day.Source, epg.Lang, groups)
+			if nil != err {
+				return nil, nil, &scrape.ParseError{URL: day.Source, Node: p.ID, Err: err}
+			}
+			for _, bc := range bcs {
+				results = append(results, bc)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,413 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Read RadioDNS service and programme information, ETSI TS 102 818 (SPI,
+// formerly EPG XML), a standardised alternative to scraping program websites.
+//
+// https://www.etsi.org/deliver/etsi_ts/102800_102899/102818/
+//
+// import "purl.mro.name/recorder/radio/scrape/spi"
+
+package spi
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+// Elements are matched by local name only, so documents of the older
+// http://www.worlddab.org/schemas/epgSchedule namespace read as well.
+const Namespace = "http://www.worlddab.org/schemas/spi"
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Document types, just what we use
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type Text struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type Names struct {
+	ShortName  []Text `xml:"shortName"`
+	MediumName []Text `xml:"mediumName"`
+	LongName   []Text `xml:"longName"`
+}
+
+type Multimedia struct {
+	URL       string `xml:"url,attr"`
+	Type      string `xml:"type,attr"` // logo_colour_square, logo_unrestricted, ...
+	MimeValue string `xml:"mimeValue,attr"`
+	Width     int    `xml:"width,attr"`
+	Height    int    `xml:"height,attr"`
+}
+
+type MediaDescription struct {
+	ShortDescription []Text       `xml:"shortDescription"`
+	LongDescription  []Text       `xml:"longDescription"`
+	Multimedia       []Multimedia `xml:"multimedia"`
+}
+
+// href is a TV-Anytime ContentCS term like urn:tva:metadata:cs:ContentCS:2011:3.1.4
+type Genre struct {
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+	Name string `xml:",chardata"`
+}
+
+type Link struct {
+	URI         string `xml:"uri,attr"`
+	MimeValue   string `xml:"mimeValue,attr"`
+	Description string `xml:"description,attr"`
+}
+
+type Bearer struct {
+	ID        string `xml:"id,attr"` // dab:de0.10b1.d3e1.0, fm:de0.d314.09580, http://...
+	Cost      int    `xml:"cost,attr"`
+	Offset    int    `xml:"offset,attr"`
+	MimeValue string `xml:"mimeValue,attr"`
+	Bitrate   int    `xml:"bitrate,attr"`
+}
+
+// time is xsd:dateTime, duration xsd:duration, actual* optional.
+type Time struct {
+	Time           string `xml:"time,attr"`
+	Duration       string `xml:"duration,attr"`
+	ActualTime     string `xml:"actualTime,attr"`
+	ActualDuration string `xml:"actualDuration,attr"`
+}
+
+type Location struct {
+	Time   []Time   `xml:"time"`
+	Bearer []Bearer `xml:"bearer"`
+}
+
+type MemberOf struct {
+	ID      string `xml:"id,attr"` // crid://...
+	ShortID string `xml:"shortId,attr"`
+	Index   int    `xml:"index,attr"`
+}
+
+type Programme struct {
+	ID      string `xml:"id,attr"`
+	ShortID string `xml:"shortId,attr"`
+	Version int    `xml:"version,attr"`
+	Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Names
+	Location         []Location         `xml:"location"`
+	MediaDescription []MediaDescription `xml:"mediaDescription"`
+	Genre            []Genre            `xml:"genre"`
+	MemberOf         []MemberOf         `xml:"memberOf"`
+	Link             []Link             `xml:"link"`
+}
+
+// A series, season or the like, see MemberOf.
+type ProgrammeGroup struct {
+	ID      string `xml:"id,attr"`
+	ShortID string `xml:"shortId,attr"`
+	Type    string `xml:"type,attr"` // series, show, ...
+	Names
+	MediaDescription []MediaDescription `xml:"mediaDescription"`
+	Genre            []Genre            `xml:"genre"`
+	MemberOf         []MemberOf         `xml:"memberOf"`
+	Link             []Link             `xml:"link"`
+}
+
+type ServiceScope struct {
+	ID string `xml:"id,attr"`
+}
+
+type Scope struct {
+	StartTime    string         `xml:"startTime,attr"`
+	EndTime      string         `xml:"endTime,attr"`
+	ServiceScope []ServiceScope `xml:"serviceScope"`
+}
+
+type Schedule struct {
+	Originator   string      `xml:"originator,attr"`
+	Version      int         `xml:"version,attr"`
+	CreationTime string      `xml:"creationTime,attr"`
+	Scope        *Scope      `xml:"scope"`
+	Programmes   []Programme `xml:"programme"`
+}
+
+// The root of a ProgrammeInformation document, <date>_PI.xml
+type EPG struct {
+	XMLName         xml.Name         `xml:"epg"`
+	Lang            string           `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	ProgrammeGroups []ProgrammeGroup `xml:"programmeGroups>programmeGroup"`
+	Schedules       []Schedule       `xml:"schedule"`
+}
+
+type RadioDNS struct {
+	FQDN              string `xml:"fqdn,attr"`
+	ServiceIdentifier string `xml:"serviceIdentifier,attr"`
+}
+
+type Service struct {
+	Version int `xml:"version,attr"`
+	Names
+	MediaDescription []MediaDescription `xml:"mediaDescription"`
+	Genre            []Genre            `xml:"genre"`
+	Link             []Link             `xml:"link"`
+	Bearer           []Bearer           `xml:"bearer"`
+	RadioDNS         *RadioDNS          `xml:"radiodns"`
+	Keywords         string             `xml:"keywords"`
+}
+
+// The root of a ServiceInformation document, SI.xml
+type ServiceInformation struct {
+	XMLName      xml.Name  `xml:"serviceInformation"`
+	Lang         string    `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Originator   string    `xml:"originator,attr"`
+	CreationTime string    `xml:"creationTime,attr"`
+	Services     []Service `xml:"services>service"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Read
+//////////////////////////////////////////////////////////////////////////////////////////
+
+func ReadEPG(r io.Reader) (ret EPG, err error) {
+	err = xml.NewDecoder(r).Decode(&ret)
+	return
+}
+
+func ReadServiceInformation(r io.Reader) (ret ServiceInformation, err error) {
+	err = xml.NewDecoder(r).Decode(&ret)
+	return
+}
+
+var durationRegExp = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// xsd:duration as SPI uses it, days, hours, minutes and seconds only, e.g. PT1H30M
+func ParseDuration(s string) (ret time.Duration, err error) {
+	m := durationRegExp.FindStringSubmatch(strings.TrimSpace(s))
+	if nil == m || "P" == m[0] || strings.HasSuffix(m[0], "T") {
+		return 0, errors.New("invalid duration '" + s + "'")
+	}
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if "" == m[1+i] {
+			continue
+		}
+		f, err := strconv.ParseFloat(m[1+i], 64)
+		if nil != err {
+			return 0, err
+		}
+		ret += time.Duration(f * float64(unit))
+	}
+	return
+}
+
+// The text in lang, else the first one.
+func pick(ts []Text, lang string) *Text {
+	for i := range ts {
+		if lang == ts[i].Lang {
+			return &ts[i]
+		}
+	}
+	if 0 < len(ts) {
+		return &ts[0]
+	}
+	return nil
+}
+
+// The longest name there is in lang, "" if none.
+func (n Names) Name(lang string) string {
+	for _, ts := range [][]Text{n.LongName, n.MediumName, n.ShortName} {
+		if t := pick(ts, lang); nil != t {
+			return strings.TrimSpace(t.Value)
+		}
+	}
+	return ""
+}
+
+// The long description in lang, else the short one, nil if none.
+func description(mds []MediaDescription, lang string) *string {
+	for _, long := range []bool{true, false} {
+		for _, md := range mds {
+			ts := md.ShortDescription
+			if long {
+				ts = md.LongDescription
+			}
+			if t := pick(ts, lang); nil != t {
+				s := strings.TrimSpace(t.Value)
+				return &s
+			}
+		}
+	}
+	return nil
+}
+
+// The widest image, preferably a logo if logos is set, nil if none.
+func image(mds []MediaDescription, logos bool) (ret *Multimedia) {
+	for _, md := range mds {
+		for i, mm := range md.Multimedia {
+			if "" == mm.URL || (logos && !strings.HasPrefix(mm.Type, "logo")) {
+				continue
+			}
+			if nil == ret || mm.Width > ret.Width {
+				ret = &md.Multimedia[i]
+			}
+		}
+	}
+	if nil == ret && logos {
+		return image(mds, false)
+	}
+	return
+}
+
+// The first web page, resolved against base, nil if none.
+func homepage(links []Link, base url.URL) (*url.URL, error) {
+	for _, l := range links {
+		if "" != l.MimeValue && "text/html" != l.MimeValue {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(l.URI))
+		if nil != err {
+			return nil, err
+		}
+		if u = base.ResolveReference(u); "http" == u.Scheme || "https" == u.Scheme {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Map to scrape types
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// Map s to a Station. Identifier is the RadioDNS service identifier,
+// Params["spi_bearer"] the first broadcast bearer.
+func (s Service) Station(lang string) (st scrape.Station, err error) {
+	if nil != s.RadioDNS {
+		st.Identifier = s.RadioDNS.ServiceIdentifier
+	}
+	st.Name = s.Names.Name(lang)
+	if st.Homepage, err = homepage(s.Link, url.URL{}); nil != err {
+		return
+	}
+	if mm := image(s.MediaDescription, true); nil != mm {
+		if st.Logo, err = url.Parse(mm.URL); nil != err {
+			return
+		}
+	}
+	for _, b := range s.Bearer {
+		if !strings.HasPrefix(b.ID, "http") {
+			st.Params = map[string]string{"spi_bearer": b.ID}
+			break
+		}
+	}
+	return
+}
+
+// The groups by id, to name the series of a Programme.
+func (e EPG) Groups() map[string]ProgrammeGroup {
+	ret := make(map[string]ProgrammeGroup, len(e.ProgrammeGroups))
+	for _, g := range e.ProgrammeGroups {
+		ret[g.ID] = g
+	}
+	return ret
+}
+
+// Map p to one Broadcast of st per location time. Source is the first web
+// page link, else base. lang is the fallback if p has no xml:lang.
+func (p Programme) Broadcasts(st scrape.Station, base url.URL, lang string, groups map[string]ProgrammeGroup) (ret []scrape.Broadcast, err error) {
+	loc := st.TimeZone
+	if nil == loc {
+		loc = time.UTC
+	}
+	if "" != p.Lang {
+		lang = p.Lang
+	}
+	bc := scrape.Broadcast{}
+	bc.Station = st
+	bc.Title = p.Names.Name(lang)
+	bc.Description = description(p.MediaDescription, lang)
+	if "" != lang {
+		bc.Language = &lang
+	}
+	for _, m := range p.MemberOf {
+		if g, ok := groups[m.ID]; ok {
+			if name := g.Names.Name(lang); "" != name {
+				bc.TitleSeries = &name
+				break
+			}
+		}
+	}
+	if mm := image(p.MediaDescription, false); nil != mm {
+		if bc.Image, err = url.Parse(mm.URL); nil != err {
+			return
+		}
+	}
+	for _, g := range p.Genre {
+		if "" != g.Href {
+			if bc.Subject, err = url.Parse(g.Href); nil != err {
+				return
+			}
+			break
+		}
+	}
+	bc.Source = base
+	u, err := homepage(p.Link, base)
+	if nil != err {
+		return
+	}
+	if nil != u {
+		bc.Source = *u
+	}
+	if "" != st.Name {
+		bc.Publisher = &st.Name
+	}
+	for _, l := range p.Location {
+		for _, t := range l.Time {
+			b := bc
+			start, dur := t.Time, t.Duration
+			if "" != t.ActualTime {
+				start = t.ActualTime
+			}
+			if "" != t.ActualDuration {
+				dur = t.ActualDuration
+			}
+			if b.Time, err = time.Parse(time.RFC3339, start); nil != err {
+				return
+			}
+			b.Time = b.Time.In(loc)
+			if "" != dur {
+				d, err := ParseDuration(dur)
+				if nil != err {
+					return ret, err
+				}
+				end := b.Time.Add(d)
+				b.DtEnd = &end
+			}
+			ret = append(ret, b)
+		}
+	}
+	return
+}
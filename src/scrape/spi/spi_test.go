@@ -0,0 +1,167 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape/spi"
+//
+package spi
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+func TestParseDuration(t *testing.T) {
+	for s, d := range map[string]time.Duration{
+		"PT1H":       time.Hour,
+		"PT55M":      55 * time.Minute,
+		"PT1H30M5S":  90*time.Minute + 5*time.Second,
+		"PT0.5S":     500 * time.Millisecond,
+		"P1DT2H":     26 * time.Hour,
+		" PT5M\n":    5 * time.Minute,
+		"P1D":        24 * time.Hour,
+		"PT10M00S":   10 * time.Minute,
+		"PT2H0M0.0S": 2 * time.Hour,
+	} {
+		v, err := ParseDuration(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, d, v, s)
+	}
+	for _, s := range []string{"", "P", "PT", "1H", "PT1Y", "P1M", "-PT1H"} {
+		_, err := ParseDuration(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestReadServiceInformation(t *testing.T) {
+	f, err := os.Open("testdata/SI.xml")
+	assert.Nil(t, err, "ouch")
+	defer f.Close()
+	si, err := ReadServiceInformation(f)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "de", si.Lang, "ouch")
+	assert.Equal(t, "Bayerischer Rundfunk", si.Originator, "ouch")
+	assert.Equal(t, 1, len(si.Services), "ouch")
+
+	st, err := si.Services[0].Station(si.Lang)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "bayern2", st.Identifier, "ouch")
+	assert.Equal(t, "Bayern 2 - Kultur und mehr", st.Name, "longName")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/", st.Homepage.String(), "not the mailto")
+	assert.Equal(t, "http://epg.example/logos/bayern2_600x600.jpg", st.Logo.String(), "widest logo")
+	assert.Equal(t, "dab:de0.10b1.d3e1.0", st.Params["spi_bearer"], "not the stream")
+	assert.Equal(t, "urn:tva:metadata:cs:ContentCS:2011:3.6.1", si.Services[0].Genre[0].Href, "ouch")
+	assert.Equal(t, "Kultur", si.Services[0].Genre[0].Name, "ouch")
+}
+
+func TestReadEPG(t *testing.T) {
+	f, err := os.Open("testdata/20151114_PI.xml")
+	assert.Nil(t, err, "ouch")
+	defer f.Close()
+	epg, err := ReadEPG(f)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "de", epg.Lang, "ouch")
+	assert.Equal(t, 1, len(epg.Schedules), "ouch")
+	assert.Equal(t, "dab:de0.10b1.d3e1.0", epg.Schedules[0].Scope.ServiceScope[0].ID, "ouch")
+	assert.Equal(t, 2, len(epg.Schedules[0].Programmes), "ouch")
+
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	st := scrape.Station{Identifier: "bayern2", Name: "Bayern 2", TimeZone: tz}
+	base := *scrape.MustParseURL("https://www.br.de/radio/bayern2/programmkalender/")
+	groups := epg.Groups()
+
+	bcs, err := epg.Schedules[0].Programmes[0].Broadcasts(st, base, epg.Lang, groups)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(bcs), "ouch")
+	bc := bcs[0]
+	assert.Equal(t, "bayern2", bc.Station.Identifier, "ouch")
+	assert.Equal(t, "2015-11-14T09:05:00+01:00", bc.Time.Format(time.RFC3339), "in the station zone")
+	assert.Equal(t, "2015-11-14T10:00:00+01:00", bc.DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "Der Zorn - Eine Todsünde?", bc.Title, "longName")
+	assert.Equal(t, "radioWissen - Das ganze Spektrum des Wissens", *bc.TitleSeries, "memberOf")
+	assert.Nil(t, bc.TitleEpisode, "ouch")
+	assert.Equal(t, "Autor: Gerhard Zach. Der Zorn gilt als Todsünde, doch ohne ihn gäbe es keine Revolte.", *bc.Description, "longDescription")
+	assert.Equal(t, "http://epg.example/img/zorn_320.jpg", bc.Image.String(), "widest")
+	assert.Equal(t, "urn:tva:metadata:cs:ContentCS:2011:3.1.4", bc.Subject.String(), "genre")
+	assert.Equal(t, "https://www.br.de/radio/bayern2/sendungen/radiowissen/zorn-100.html", bc.Source.String(), "link")
+	assert.Equal(t, "de", *bc.Language, "ouch")
+	assert.Equal(t, "Bayern 2", *bc.Publisher, "ouch")
+	assert.Nil(t, bc.Validate(), "ouch")
+
+	bcs, err = epg.Schedules[0].Programmes[1].Broadcasts(st, base, epg.Lang, groups)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(bcs), "one per location time")
+	assert.Equal(t, "2015-11-14T10:00:30+01:00", bcs[0].Time.Format(time.RFC3339), "actualTime")
+	assert.Equal(t, "2015-11-14T10:05:30+01:00", bcs[0].DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "2015-11-14T11:00:00+01:00", bcs[1].Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "Nachrichten", bcs[1].Title, "mediumName")
+	assert.Nil(t, bcs[1].TitleSeries, "ouch")
+	assert.Nil(t, bcs[1].Description, "ouch")
+	assert.Nil(t, bcs[1].Image, "ouch")
+	assert.Equal(t, base, bcs[1].Source, "no link")
+}
+
+func TestBearerPath(t *testing.T) {
+	assert.Equal(t, "dab/de0/10b1/d3e1/0", bearerPath("dab:de0.10b1.d3e1.0"), "ouch")
+	assert.Equal(t, "fm/de0/d314/08855", bearerPath("fm:de0.d314.08855"), "ouch")
+}
+
+// LoadStations() registers bayern2, Scrape() its first day offline, see testdata/fixtures/index.txt
+func TestSource(t *testing.T) {
+	defer func(c *http.Client) { scrape.Client = c }(scrape.Client)
+	fx := &scrape.Fixtures{Dir: "testdata/fixtures"}
+	scrape.Client = &http.Client{Transport: fx}
+	defer func(n func() time.Time) { scrape.Now = n }(scrape.Now)
+	now, err := fx.Recorded()
+	assert.Nil(t, err, "ouch")
+	scrape.Now = func() time.Time { return now }
+
+	assert.Nil(t, scrape.LoadStations("testdata/stations"), "ouch")
+	s, err := scrape.NewScraper("bayern2")
+	assert.Nil(t, err, "ouch")
+	jobs, _, err := s.Scrape(context.Background())
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 5, len(jobs), "ouch")
+	assert.Equal(t, "https://epg.example/radiodns/spi/3.1/dab/de0/10b1/d3e1/0/20151114_PI.xml", jobs[0].(*timeURL).Source.String(), "ouch")
+	assert.Equal(t, "https://epg.example/radiodns/spi/3.1/dab/de0/10b1/d3e1/0/20151117_PI.xml", jobs[2].(*timeURL).Source.String(), "ouch")
+
+	bcs, err := scrape.Crawl(context.Background(), jobs[0], nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 3, len(bcs), "ouch")
+	bc, _ := scrape.AsBroadcast(bcs[0])
+	assert.Equal(t, "bayern2", bc.Station.Identifier, "ouch")
+	assert.Equal(t, "Der Zorn - Eine Todsünde?", bc.Title, "ouch")
+	assert.Equal(t, "Europe/Berlin", bc.Time.Location().String(), "ouch")
+}
+
+func TestSourceNoBearer(t *testing.T) {
+	err := scrape.LoadStations("testdata/nobearer")
+	assert.NotNil(t, err, "ouch")
+	assert.Equal(t, "testdata/nobearer/bayern2-nobearer/app/station.cfg: scrape_source 'spi' needs spi_bearer", err.Error(), "ouch")
+	_, err = scrape.NewScraper("bayern2-nobearer")
+	assert.NotNil(t, err, "not registered")
+}
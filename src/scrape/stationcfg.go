@@ -0,0 +1,266 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Read station metadata from htdocs/stations/<id>/app/station.cfg and
+// htdocs/stations/<id>/about.rdf, so changing e.g. a program url is a data
+// change, not a recompile.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	rxLuaComment = regexp.MustCompile(`--[^\n]*`)
+	rxLuaField   = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(?:'((?:[^'\\]|\\.)*)'|"((?:[^"\\]|\\.)*)")\s*(?:[,;]|$)`)
+	rxLuaEscape  = regexp.MustCompile(`\\(.)`)
+	rxDayStart   = regexp.MustCompile(`^([01]\d|2[0-3])([0-5]\d)$`)
+)
+
+// Parse the subset of lua used by station.cfg: one table of string values.
+func parseLuaTable(data []byte) (ret map[string]string, err error) {
+	s := strings.TrimSpace(rxLuaComment.ReplaceAllString(string(data), ""))
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("expected a single {...} table")
+	}
+	s = s[1 : len(s)-1]
+	ret = make(map[string]string)
+	for "" != strings.TrimSpace(s) {
+		m := rxLuaField.FindStringSubmatchIndex(s)
+		if nil == m {
+			near := strings.TrimSpace(s)
+			if i := strings.IndexByte(near, '\n'); 0 <= i {
+				near = near[:i]
+			}
+			return nil, fmt.Errorf("can't parse near '%s'", near)
+		}
+		key := s[m[2]:m[3]]
+		val := ""
+		if 0 <= m[4] {
+			val = s[m[4]:m[5]]
+		} else {
+			val = s[m[6]:m[7]]
+		}
+		ret[key] = rxLuaEscape.ReplaceAllString(val, "$1")
+		s = s[m[1]:]
+	}
+	return
+}
+
+type rdfResource struct {
+	Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# resource,attr"`
+}
+
+type aboutRdf struct {
+	Descriptions []struct {
+		About    string      `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+		Name     string      `xml:"http://xmlns.com/foaf/0.1/ name"`
+		Homepage rdfResource `xml:"http://xmlns.com/foaf/0.1/ homepage"`
+		Logo     rdfResource `xml:"http://xmlns.com/foaf/0.1/ logo"`
+	} `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# Description"`
+}
+
+func absURL(file, key, s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if nil != err {
+		return nil, fmt.Errorf("%s: %s: %s", file, key, err)
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("%s: %s isn't absolute: '%s'", file, key, s)
+	}
+	return u, nil
+}
+
+func loadStationCfg(dir string) (cfg string, m map[string]string, err error) {
+	cfg = filepath.Join(dir, "app", "station.cfg")
+	data, err := ioutil.ReadFile(cfg)
+	if nil != err {
+		return
+	}
+	if m, err = parseLuaTable(data); nil != err {
+		err = fmt.Errorf("%s: %s", cfg, err)
+	}
+	return
+}
+
+// Read the station in dir (e.g. htdocs/stations/b2). app/station.cfg is
+// mandatory, about.rdf optional. 'scrape_url' in station.cfg takes precedence
+// over 'program_url' for scrapers needing a different entry point.
+func LoadStation(dir string) (ret Station, err error) {
+	ret.Identifier = filepath.Base(dir)
+	cfg, m, err := loadStationCfg(dir)
+	if nil != err {
+		return
+	}
+	ret.Params = m
+	for _, key := range []string{"title", "program_url", "day_start", "timezone"} {
+		if "" == m[key] {
+			return ret, fmt.Errorf("%s: station %s not set", cfg, key)
+		}
+	}
+	ret.Name = m["title"]
+	dayStart := rxDayStart.FindStringSubmatch(m["day_start"])
+	if nil == dayStart {
+		return ret, fmt.Errorf("%s: day_start isn't hhmm: '%s'", cfg, m["day_start"])
+	}
+	ret.CloseDown = dayStart[1] + ":" + dayStart[2]
+	if ret.TimeZone, err = time.LoadLocation(m["timezone"]); nil != err {
+		return ret, fmt.Errorf("%s: timezone: %s", cfg, err)
+	}
+	key := "program_url"
+	if "" != m["scrape_url"] {
+		key = "scrape_url"
+	}
+	if ret.ProgramURL, err = absURL(cfg, key, m[key]); nil != err {
+		return
+	}
+	if ret.Limit, err = ret.paramsLimit(nil); nil != err {
+		return ret, fmt.Errorf("%s: %s", cfg, err)
+	}
+
+	rdf := filepath.Join(dir, "about.rdf")
+	data, err := ioutil.ReadFile(rdf)
+	if os.IsNotExist(err) {
+		return ret, nil
+	} else if nil != err {
+		return
+	}
+	about := aboutRdf{}
+	if err = xml.Unmarshal(data, &about); nil != err {
+		return ret, fmt.Errorf("%s: %s", rdf, err)
+	}
+	for _, d := range about.Descriptions {
+		if "." != d.About {
+			continue
+		}
+		if "" != d.Name {
+			ret.Name = d.Name
+		}
+		if "" != d.Homepage.Resource {
+			if ret.Homepage, err = absURL(rdf, "foaf:homepage", d.Homepage.Resource); nil != err {
+				return
+			}
+		}
+		if "" != d.Logo.Resource {
+			if ret.Logo, err = absURL(rdf, "foaf:logo", d.Logo.Resource); nil != err {
+				return
+			}
+		}
+	}
+	return
+}
+
+// lim, or DefaultHostLimit if nil, with 'host_concurrency' and 'host_interval'
+// (e.g. '250ms') from Params where given. lim if neither is.
+func (s Station) paramsLimit(lim *HostLimit) (*HostLimit, error) {
+	c, i := s.Params["host_concurrency"], s.Params["host_interval"]
+	if "" == c && "" == i {
+		return lim, nil
+	}
+	ret := DefaultHostLimit
+	if nil != lim {
+		ret = *lim
+	}
+	if "" != c {
+		n, err := strconv.Atoi(c)
+		if nil != err || n < 0 {
+			return nil, fmt.Errorf("host_concurrency isn't a number: '%s'", c)
+		}
+		ret.Concurrency = n
+	}
+	if "" != i {
+		d, err := time.ParseDuration(i)
+		if nil != err || d < 0 {
+			return nil, fmt.Errorf("host_interval isn't a duration like '250ms': '%s'", i)
+		}
+		ret.Interval = d
+	}
+	return &ret, nil
+}
+
+// Update the registered stations from dir/<id>/ where present, keeping the
+// built-in values otherwise. The built-in Limit is the default for
+// host_concurrency and host_interval. Registers the stations whose station.cfg names
+// a 'scrape_source', see RegisterSource.
+func LoadStations(dir string) error {
+	if _, err := os.Stat(dir); nil != err {
+		return err
+	}
+	for _, builtin := range Stations() {
+		sub := filepath.Join(dir, builtin.Identifier)
+		if _, err := os.Stat(filepath.Join(sub, "app", "station.cfg")); os.IsNotExist(err) {
+			continue
+		}
+		st, err := LoadStation(sub)
+		if nil != err {
+			return err
+		}
+		st.Limit, _ = st.paramsLimit(builtin.Limit) // LoadStation checked the values
+		registry.Lock()
+		reg := registry.m[st.Identifier]
+		reg.station = st
+		registry.m[st.Identifier] = reg
+		registry.Unlock()
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if nil != err {
+		return err
+	}
+	for _, fi := range fis {
+		registry.Lock()
+		_, known := registry.m[fi.Name()]
+		registry.Unlock()
+		if known || !fi.IsDir() {
+			continue // updated above
+		}
+		sub := filepath.Join(dir, fi.Name())
+		cfg, m, err := loadStationCfg(sub)
+		if nil != err || "" == m["scrape_source"] {
+			continue // somebody else's, e.g. a lua scraper
+		}
+		src, ok := sourceFor(m["scrape_source"])
+		if !ok {
+			return fmt.Errorf("%s: unknown scrape_source '%s'", cfg, m["scrape_source"])
+		}
+		for _, k := range src.required {
+			if "" == m[k] {
+				return fmt.Errorf("%s: scrape_source '%s' needs %s", cfg, m["scrape_source"], k)
+			}
+		}
+		st, err := LoadStation(sub)
+		if nil != err {
+			return err
+		}
+		Register(st, src.factory)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLuaTable(t *testing.T) {
+	m, err := parseLuaTable([]byte("{\n  title = 'Bayern 2', -- comment\n  quote = \"it's\";\n  esc = 'a\\'b'\n}\n"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, map[string]string{"title": "Bayern 2", "quote": "it's", "esc": "a'b"}, m, "ouch")
+
+	_, err = parseLuaTable([]byte("title = 'Bayern 2'"))
+	assert.Equal(t, "expected a single {...} table", err.Error(), "ouch")
+	_, err = parseLuaTable([]byte("{ title = 42 }"))
+	assert.Equal(t, "can't parse near 'title = 42'", err.Error(), "ouch")
+}
+
+func TestLoadStation(t *testing.T) {
+	s, err := LoadStation("testdata/stations/b2")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "b2", s.Identifier, "ouch")
+	assert.Equal(t, "Bayern 2", s.Name, "ouch")
+	assert.Equal(t, "05:00", s.CloseDown, "ouch")
+	assert.Equal(t, "Europe/Berlin", s.TimeZone.String(), "ouch")
+	assert.Equal(t, "http://www.br.de/radio/bayern2/service/programm/index.html", s.ProgramURL.String(), "scrape_url wins")
+	assert.Equal(t, "http://bayern2.de/", s.Homepage.String(), "ouch")
+	assert.Equal(t, "https://upload.wikimedia.org/wikipedia/de/2/27/Bayern_2_%282007%29.svg", s.Logo.String(), "ouch")
+	assert.Equal(t, HostLimit{Concurrency: 2, Interval: 500 * time.Millisecond}, *s.Limit, "DefaultHostLimit plus host_interval")
+}
+
+func TestLoadStationInvalid(t *testing.T) {
+	for dir, msg := range map[string]string{
+		"bad-tz":       "testdata/stations/bad-tz/app/station.cfg: timezone: unknown time zone Europe/Bärlin",
+		"bad-daystart": "testdata/stations/bad-daystart/app/station.cfg: day_start isn't hhmm: '5'",
+		"bad-syntax":   "testdata/stations/bad-syntax/app/station.cfg: can't parse near 'title = Bayern 2,'",
+		"no-url":       "testdata/stations/no-url/app/station.cfg: station program_url not set",
+		"bad-limit":    "testdata/stations/bad-limit/app/station.cfg: host_interval isn't a duration like '250ms': 'soon'",
+	} {
+		_, err := LoadStation("testdata/stations/" + dir)
+		assert.Equal(t, msg, err.Error(), dir)
+	}
+}
+
+func TestLoadStations(t *testing.T) {
+	factory := func(st Station) Scraper { return nopScraper(st.Identifier) }
+	limit := &HostLimit{Concurrency: 1}
+	Register(Station{Identifier: "b2", Name: "built-in", ProgramURL: MustParseURL("http://example.com/"), Limit: limit}, factory)
+	Register(Station{Identifier: "no-cfg", Name: "built-in"}, factory)
+	defer func() {
+		registry.Lock()
+		delete(registry.m, "b2")
+		delete(registry.m, "no-cfg")
+		delete(registry.m, "configured")
+		registry.Unlock()
+		sources.Lock()
+		delete(sources.m, "test-source")
+		sources.Unlock()
+	}()
+
+	assert.Equal(t, "testdata/stations/configured/app/station.cfg: unknown scrape_source 'test-source'", LoadStations("testdata/stations").Error(), "ouch")
+	RegisterSource("test-source", factory)
+	assert.Nil(t, LoadStations("testdata/stations"), "ouch")
+	s, err := NewScraper("configured")
+	assert.Nil(t, err, "registered from data only")
+	assert.Equal(t, nopScraper("configured"), s, "ouch")
+	for _, st := range Stations() {
+		switch st.Identifier {
+		case "b2":
+			assert.Equal(t, "Bayern 2", st.Name, "from about.rdf")
+			assert.Equal(t, "http://www.br.de/radio/bayern2/service/programm/index.html", st.ProgramURL.String(), "ouch")
+			assert.Equal(t, HostLimit{Concurrency: 1, Interval: 500 * time.Millisecond}, *st.Limit, "host_interval, built-in host_concurrency")
+			assert.Equal(t, HostLimit{Concurrency: 1}, *limit, "built-in untouched")
+		case "no-cfg":
+			assert.Equal(t, "built-in", st.Name, "kept")
+		case "configured":
+			assert.Equal(t, "06:00", st.CloseDown, "ouch")
+			assert.Equal(t, "configured.example.com", st.Params["xmltv_channel"], "ouch")
+			assert.Equal(t, HostLimit{Concurrency: 1}, *st.Limit, "host_concurrency")
+		}
+	}
+	assert.NotNil(t, LoadStations("testdata/nonexistent"), "ouch")
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Per station numbers of a scrape run, e.g. for a json run report.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type Stats struct {
+	mu          sync.Mutex
+	Station     string    `json:"station"`
+	Jobs        int       `json:"jobs"`
+	Retries     int       `json:"retries"`
+	Pages       int       `json:"pages"`        // parsed, see ReportLoad
+	PagesCached int       `json:"pages_cached"` // of Pages
+	BytesLoaded int64     `json:"bytes_loaded"` // network, compressed
+	BytesParsed int64     `json:"bytes_parsed"`
+	Broadcasts  int       `json:"broadcasts"`
+	HttpErrors  int       `json:"http_errors"`
+	ParseErrors int       `json:"parse_errors"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	WallTime    float64   `json:"wall_time"` // seconds from Start to End
+}
+
+func NewStats(station string) *Stats {
+	return &Stats{Station: station}
+}
+
+type statsKey struct{}
+
+// Have HttpGetBody and ReportLoad account to st.
+func WithStats(ctx context.Context, st *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, st)
+}
+
+func statsFrom(ctx context.Context) *Stats {
+	st, _ := ctx.Value(statsKey{}).(*Stats)
+	return st
+}
+
+func (st *Stats) page(cr0 *CountingReader, cr *CountingReader) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.Pages++
+	if cr0.Cached {
+		st.PagesCached++
+	} else {
+		st.BytesLoaded += cr0.TotalBytes
+	}
+	st.BytesParsed += cr.TotalBytes
+}
+
+// Account a finished job, started at t0, with its outcome.
+func (st *Stats) Job(t0 time.Time, retries int, broadcasts int, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	t1 := time.Now()
+	if st.Start.IsZero() || t0.Before(st.Start) {
+		st.Start = t0
+	}
+	if st.End.Before(t1) {
+		st.End = t1
+	}
+	st.WallTime = st.End.Sub(st.Start).Seconds()
+	st.Jobs++
+	st.Retries += retries
+	st.Broadcasts += broadcasts
+	if nil != err {
+		if isHttpError(err) {
+			st.HttpErrors++
+		} else {
+			st.ParseErrors++
+		}
+	}
+}
+
+// A snapshot safe to marshal while jobs are still running.
+func (st *Stats) Snapshot() *Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return &Stats{
+		Station:     st.Station,
+		Jobs:        st.Jobs,
+		Retries:     st.Retries,
+		Pages:       st.Pages,
+		PagesCached: st.PagesCached,
+		BytesLoaded: st.BytesLoaded,
+		BytesParsed: st.BytesParsed,
+		Broadcasts:  st.Broadcasts,
+		HttpErrors:  st.HttpErrors,
+		ParseErrors: st.ParseErrors,
+		Start:       st.Start,
+		End:         st.End,
+		WallTime:    st.WallTime,
+	}
+}
+
+// Fetching failed, as opposed to parsing what was fetched.
+func isHttpError(err error) bool {
+	switch err.(type) {
+	case *FetchError, *StatusError, *url.Error:
+		return true
+	}
+	return IsTransient(err) || context.Canceled == err
+}
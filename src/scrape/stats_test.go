@@ -0,0 +1,85 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>0123456789</html>"))
+	}))
+	defer srv.Close()
+
+	st := NewStats("b2")
+	ctx := WithStats(context.Background(), st)
+	for i := 0; i < 2; i++ {
+		bo, cr0, err := HttpGetBody(ctx, *MustParseURL(srv.URL))
+		assert.Nil(t, err, "ouch")
+		cr := NewCountingReader(bo)
+		ioutil.ReadAll(cr)
+		bo.Close()
+		ReportLoad("🐦", cr0, cr, *MustParseURL(srv.URL))
+	}
+	s := st.Snapshot()
+	assert.Equal(t, 2, s.Pages, "ouch")
+	assert.Equal(t, 0, s.PagesCached, "ouch")
+	assert.Equal(t, int64(46), s.BytesLoaded, "ouch")
+	assert.Equal(t, int64(46), s.BytesParsed, "ouch")
+
+	// without stats in the context nothing is accounted
+	bo, cr0, _ := HttpGetBody(context.Background(), *MustParseURL(srv.URL))
+	ioutil.ReadAll(bo)
+	bo.Close()
+	ReportLoad("🐦", cr0, cr0, *MustParseURL(srv.URL))
+	assert.Equal(t, 2, st.Snapshot().Pages, "ouch")
+}
+
+func TestStatsJob(t *testing.T) {
+	st := NewStats("b2")
+	t0 := time.Now().Add(-time.Second)
+	st.Job(t0, 0, 12, nil)
+	st.Job(t0, 2, 0, &StatusError{StatusCode: 503, Status: "503 Service Unavailable"})
+	st.Job(t0.Add(500*time.Millisecond), 0, 0, errors.New("Couldn't parse <a>"))
+
+	s := st.Snapshot()
+	assert.Equal(t, "b2", s.Station, "ouch")
+	assert.Equal(t, 3, s.Jobs, "ouch")
+	assert.Equal(t, 2, s.Retries, "ouch")
+	assert.Equal(t, 12, s.Broadcasts, "ouch")
+	assert.Equal(t, 1, s.HttpErrors, "ouch")
+	assert.Equal(t, 1, s.ParseErrors, "ouch")
+	assert.Equal(t, t0, s.Start, "ouch")
+	assert.True(t, 1.0 <= s.WallTime, "ouch")
+}
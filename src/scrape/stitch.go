@@ -0,0 +1,88 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Stitch the days of day-list scrapers, see Stitch.
+//
+// import "purl.mro.name/recorder/radio/scrape"
+
+package scrape
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A hole or double booking between two consecutive broadcasts of a station.
+type Seam struct {
+	Station string
+	From    time.Time // gap: end of Before, overlap: start of After
+	To      time.Time // gap: start of After, overlap: end of Before
+	Overlap bool
+	Before  string // title
+	After   string // title
+}
+
+func (s Seam) String() string {
+	kind := "gap"
+	if s.Overlap {
+		kind = "overlap"
+	}
+	return fmt.Sprintf("%s %s %s - %s (%s) between '%s' and '%s'", kind, s.Station, s.From.Format("2006-01-02 15:04"), s.To.Format("2006-01-02 15:04"), s.To.Sub(s.From), s.Before, s.After)
+}
+
+// Order the broadcasts by station and start and let each one with a missing
+// or guessed DtEnd (see Broadcast.DtEndGuessed) end where the next one
+// starts, unless that's more than MaxDuration away. Day-list scrapers know
+// the end of a day's last broadcast only from the next day's first one.
+//
+// Reports the remaining gaps (up to MaxDuration, longer ones are days not
+// scraped) and overlaps between consecutive broadcasts.
+func Stitch(bcs []Broadcast) (ret []Broadcast, seams []Seam) {
+	ret = make([]Broadcast, len(bcs))
+	copy(ret, bcs)
+	sort.SliceStable(ret, func(a, b int) bool {
+		if ret[a].Station.Identifier != ret[b].Station.Identifier {
+			return ret[a].Station.Identifier < ret[b].Station.Identifier
+		}
+		return ret[a].Time.Before(ret[b].Time)
+	})
+	for i := 0; i+1 < len(ret); i++ {
+		a, b := &ret[i], ret[i+1]
+		if a.Station.Identifier != b.Station.Identifier || !a.Time.Before(b.Time) {
+			continue
+		}
+		if (nil == a.DtEnd || a.DtEndGuessed) && b.Time.Sub(a.Time) <= MaxDuration {
+			end := b.Time
+			a.DtEnd, a.DtEndGuessed = &end, false
+		}
+		if nil == a.DtEnd {
+			continue
+		}
+		switch {
+		case b.Time.Sub(*a.DtEnd) > MaxDuration:
+			// days in between not scraped, e.g. see IncrementalNows
+		case a.DtEnd.Before(b.Time):
+			seams = append(seams, Seam{Station: a.Station.Identifier, From: *a.DtEnd, To: b.Time, Before: a.Title, After: b.Title})
+		case b.Time.Before(*a.DtEnd):
+			seams = append(seams, Seam{Station: a.Station.Identifier, From: b.Time, To: *a.DtEnd, Overlap: true, Before: a.Title, After: b.Title})
+		}
+	}
+	return
+}
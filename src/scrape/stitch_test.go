@@ -0,0 +1,79 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape"
+//
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStitch(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(y int, mo time.Month, d, h, mi int) time.Time { return time.Date(y, mo, d, h, mi, 0, 0, berlin) }
+	bc := func(station string, start time.Time, title string, end *time.Time, guessed bool) Broadcast {
+		b := Broadcast{}
+		b.Station.Identifier = station
+		b.Time = start
+		b.Title = title
+		b.DtEnd = end
+		b.DtEndGuessed = guessed
+		return b
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	// DST ends in the night of Oct 25th 2015, 03:00 CEST -> 02:00 CET
+	bcs, seams := Stitch([]Broadcast{
+		bc("rf", at(2015, 10, 25, 0, 30), "Spät", ptr(at(2015, 10, 25, 6, 0)), false),
+		bc("rf", at(2015, 10, 24, 23, 0), "Nacht", ptr(at(2015, 10, 25, 0, 0)), true),
+		bc("m945", at(2015, 10, 24, 23, 30), "Andere Station", ptr(at(2015, 10, 25, 0, 0)), true),
+		bc("rf", at(2015, 10, 25, 6, 0), "Morgen", ptr(at(2015, 10, 26, 0, 0)), true),
+		bc("rf", at(2015, 11, 1, 6, 0), "Eine Woche später", nil, false),
+	})
+	assert.Equal(t, 0, len(seams), "ouch")
+	assert.Equal(t, 5, len(bcs), "ouch")
+	assert.Equal(t, "Andere Station", bcs[0].Title, "ordered by station")
+	assert.True(t, bcs[0].DtEndGuessed, "nothing to stitch to")
+	assert.Equal(t, "Nacht", bcs[1].Title, "ordered by start")
+	assert.Equal(t, at(2015, 10, 25, 0, 30), *bcs[1].DtEnd, "fixed from the next day")
+	assert.False(t, bcs[1].DtEndGuessed, "ouch")
+	assert.Equal(t, 6*time.Hour+30*time.Minute, bcs[2].DtEnd.Sub(bcs[2].Time), "the night is an hour longer")
+	assert.Equal(t, at(2015, 10, 26, 0, 0), *bcs[3].DtEnd, "the next week is too far off")
+	assert.True(t, bcs[3].DtEndGuessed, "ouch")
+	assert.Nil(t, bcs[4].DtEnd, "ouch")
+
+	// DST starts in the night of Mar 27th 2016, 02:00 CET -> 03:00 CEST
+	bcs, seams = Stitch([]Broadcast{
+		bc("rf", at(2016, 3, 26, 23, 0), "Nacht", ptr(at(2016, 3, 27, 0, 0)), true),
+		bc("rf", at(2016, 3, 27, 5, 0), "Morgen", ptr(at(2016, 3, 27, 9, 0)), false),
+		bc("rf", at(2016, 3, 27, 9, 30), "Vormittag", ptr(at(2016, 3, 27, 13, 0)), false),
+		bc("rf", at(2016, 3, 27, 12, 0), "Mittag", ptr(at(2016, 3, 27, 14, 0)), false),
+	})
+	assert.Equal(t, 5*time.Hour, bcs[0].DtEnd.Sub(bcs[0].Time), "the night is an hour shorter")
+	assert.Equal(t, 2, len(seams), "ouch")
+	assert.Equal(t, "gap rf 2016-03-27 09:00 - 2016-03-27 09:30 (30m0s) between 'Morgen' and 'Vormittag'", seams[0].String(), "ouch")
+	assert.Equal(t, "overlap rf 2016-03-27 12:00 - 2016-03-27 13:00 (1h0m0s) between 'Vormittag' and 'Mittag'", seams[1].String(), "ouch")
+}
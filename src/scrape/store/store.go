@@ -0,0 +1,108 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Write broadcasts into the on-disk store 'stations/<id>.xml' just like
+// htdocs/app/Broadcast.lua save_xml does.
+//
+// import "purl.mro.name/recorder/radio/scrape/store"
+
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+// Mirror string:escape_url from recorder-plumbing.lua
+func escapeUrl(s string) string {
+	var buf bytes.Buffer
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+			buf.WriteByte(c)
+		case strings.IndexByte("_./:-", c) >= 0:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02x", c)
+		}
+	}
+	return buf.String()
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// The directory containing 'stations/'
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type Store struct {
+	Root string
+}
+
+// Path of the xml file below Root.
+func (s Store) Filename(bc scrape.Broadcast) string {
+	return filepath.Join(s.Root, "stations", filepath.FromSlash(pbmi.Identifier(bc))+".xml")
+}
+
+// Write the broadcast xml unless the file already has exactly that content.
+// Changes get logged to stations/modified.ttl and stations/<station>/modified.ttl
+func (s Store) Save(bc scrape.Broadcast) (file string, changed bool, err error) {
+	var buf bytes.Buffer
+	if err = pbmi.Write(&buf, bc); nil != err {
+		return
+	}
+	file = s.Filename(bc)
+	if old, e := ioutil.ReadFile(file); nil == e && bytes.Equal(old, buf.Bytes()) {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); nil != err {
+		return
+	}
+	if err = ioutil.WriteFile(file, buf.Bytes(), 0644); nil != err {
+		return
+	}
+	changed = true
+	err = s.logChange(bc, time.Now())
+	return
+}
+
+// Mirror Broadcast:log_change from Broadcast.lua
+func (s Store) logChange(bc scrape.Broadcast, now time.Time) (err error) {
+	id := escapeUrl(pbmi.Identifier(bc))
+	stamp := now.UTC().Format("2006-01-02T15:04:05Z")
+	f := func(file string, subject string) error {
+		w, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if nil != err {
+			return err
+		}
+		defer w.Close()
+		_, err = fmt.Fprintf(w, "<%s> <http://purl.org/dc/terms/modified> \"%s\" .\n", subject, stamp)
+		return err
+	}
+	if err = f(filepath.Join(s.Root, "stations", "modified.ttl"), id); nil != err {
+		return
+	}
+	return f(filepath.Join(s.Root, "stations", bc.Station.Identifier, "modified.ttl"), "../"+id)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape/store"
+//
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+	"purl.mro.name/recorder/radio/scrape/pbmi"
+)
+
+func broadcastB2() scrape.Broadcast {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	de := "de"
+	episode := "anspruchsvoll - entspannt - weltoffen"
+	description := "anspruchsvoll - entspannt - weltoffen\nMit Riegler Hias feat. D'Hundskrippln, Rebekka Bakken, Randy Newman und vielen mehr\nModeration: Thomas Mehringer"
+	author := "Bayerischer Rundfunk"
+	dtEnd := time.Date(2016, time.August, 25, 18, 30, 0, 0, tz)
+	return scrape.Broadcast{
+		BroadcastURL: scrape.BroadcastURL{
+			TimeURL: scrape.TimeURL{
+				Time:    time.Date(2016, time.August, 25, 18, 5, 0, 0, tz),
+				Source:  *scrape.MustParseURL("http://www.br.de/radio/bayern2/programmkalender/ausstrahlung-772436.html"),
+				Station: scrape.Station{Identifier: "b2", Name: "Bayern 2", TimeZone: tz},
+			},
+			Title: "Bayern 2-radioMusik",
+		},
+		Language:     &de,
+		TitleEpisode: &episode,
+		DtEnd:        &dtEnd,
+		Subject:      scrape.MustParseURL("http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/index.html"),
+		Image:        scrape.MustParseURL("http://www.br.de/radio/bayern2/musik/bayern2-radiomusik/rebekka-bakken-102~_v-img__16__9__m_-4423061158a17f4152aef84861ed0243214ae6e7.jpg?version=64958"),
+		Description:  &description,
+		Author:       &author,
+	}
+}
+
+func TestEscapeUrl(t *testing.T) {
+	assert.Equal(t, "b2/2016/08/25/1805%20Bayern%202-radioMusik", escapeUrl("b2/2016/08/25/1805 Bayern 2-radioMusik"), "ouch")
+	assert.Equal(t, "%c3%a4", escapeUrl("ä"), "ouch")
+}
+
+func TestSaveIfChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.Nil(t, err, "ouch")
+	defer os.RemoveAll(dir)
+
+	st := Store{Root: dir}
+	bc := broadcastB2()
+
+	file, changed, err := st.Save(bc)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, changed, "ouch")
+	assert.Equal(t, filepath.Join(dir, "stations", "b2", "2016", "08", "25", "1805 Bayern 2-radioMusik.xml"), file, "ouch")
+	fi0, err := os.Stat(file)
+	assert.Nil(t, err, "ouch")
+	bc1, err := pbmi.ReadFile(file)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, pbmi.Identifier(bc), pbmi.Identifier(bc1), "ouch")
+
+	file, changed, err = st.Save(bc)
+	assert.Nil(t, err, "ouch")
+	assert.False(t, changed, "ouch")
+	fi1, err := os.Stat(file)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, fi0.ModTime(), fi1.ModTime(), "ouch")
+
+	author := "BR"
+	bc.Author = &author
+	_, changed, err = st.Save(bc)
+	assert.Nil(t, err, "ouch")
+	assert.True(t, changed, "ouch")
+
+	ttl, err := ioutil.ReadFile(filepath.Join(dir, "stations", "modified.ttl"))
+	assert.Nil(t, err, "ouch")
+	lines := strings.Split(strings.TrimSpace(string(ttl)), "\n")
+	assert.Equal(t, 2, len(lines), "ouch")
+	assert.True(t, strings.HasPrefix(lines[0], "<b2/2016/08/25/1805%20Bayern%202-radioMusik> <http://purl.org/dc/terms/modified> \""), "ouch")
+
+	ttl, err = ioutil.ReadFile(filepath.Join(dir, "stations", "b2", "modified.ttl"))
+	assert.Nil(t, err, "ouch")
+	assert.True(t, strings.HasPrefix(string(ttl), "<../b2/2016/08/25/1805%20Bayern%202-radioMusik> "), "ouch")
+}
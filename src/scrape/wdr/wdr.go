@@ -24,11 +24,13 @@
 package wdr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -49,10 +51,12 @@ type station r.Station
 //
 // Returns a instance conforming to 'scrape.Scraper'
 func Station(identifier string) *station {
+	// one json per day and a page per broadcast, keep www.wdr.de happy
+	limit := &r.HostLimit{Concurrency: 2, Interval: 250 * time.Millisecond}
 	switch identifier {
 	case
 		"wdr5":
-		s := station(r.Station{Name: "WDR 5", CloseDown: "00:00", ProgramURL: r.MustParseURL("http://www.wdr.de/programmvorschau/ajax/wdr5/uebersicht/"), Identifier: identifier, TimeZone: localLoc})
+		s := station(r.Station{Name: "WDR 5", CloseDown: "00:00", ProgramURL: r.MustParseURL("http://www.wdr.de/programmvorschau/ajax/wdr5/uebersicht/"), Identifier: identifier, TimeZone: localLoc, Limit: limit})
 		return &s
 	}
 	return nil
@@ -67,10 +71,13 @@ func (s *station) Matches(nows []time.Time) (ok bool) {
 }
 
 // Synthesise the day urls for incremental scraping.
-func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	now := time.Now()
+func (s *station) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	now := r.Now()
 	for _, t0 := range r.IncrementalNows(now) {
-		day, _ := s.dayURLForDate(t0)
+		day, err := s.dayURLForDate(t0)
+		if nil != err {
+			return nil, nil, err
+		}
 		jobs = append(jobs, r.Scraper(*day))
 	}
 	return
@@ -79,9 +86,13 @@ func (s *station) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error
 ///////////////////////////////////////////////////////////////////////
 // https://www.wdr.de/programmvorschau/ajax/alle/uebersicht/2016-07-23/
 func (s *station) dayURLForDate(day time.Time) (ret *timeURL, err error) {
+	u, err := url.Parse(s.ProgramURL.String() + day.Format("2006-01-02/"))
+	if nil != err {
+		return
+	}
 	r := timeURL(r.TimeURL{
 		Time:    time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.TimeZone),
-		Source:  *r.MustParseURL(s.ProgramURL.String() + day.Format("2006-01-02/")),
+		Source:  *u,
 		Station: r.Station(*s),
 	})
 	ret = &r
@@ -97,8 +108,8 @@ func (day timeURL) Matches(nows []time.Time) (ok bool) {
 	return true
 }
 
-func (day timeURL) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := day.parseBroadcastsFromJsonURL()
+func (day timeURL) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := day.parseBroadcastsFromJsonURL(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			// queue to amend details
@@ -118,6 +129,9 @@ func init() {
 	if nil != err {
 		panic(err)
 	}
+	r.Register(r.Station(*Station("wdr5")), func(st r.Station) r.Scraper { s := station(st); return &s })
+	r.RegisterLevel("wdr5", "day", func(tu r.TimeURL) r.Scraper { return timeURL(tu) })
+	r.RegisterLevel("wdr5", "broadcast", func(tu r.TimeURL) r.Scraper { return &broadcast{BroadcastURL: r.BroadcastURL{TimeURL: tu}} })
 }
 
 /////////////////////////////////////////////////////////////////////////////
@@ -138,10 +152,14 @@ func (day *timeURL) parseBroadcastsFromJsonData(programm wdrProgramm) (ret []*br
 	publisher := "Westdeutscher Rundfunk"
 	empty := ""
 	for _, b := range programm.Sendungen {
+		epg, err := url.Parse(b.EpgLink)
+		if nil != err {
+			return nil, &r.ParseError{URL: day.Source, Node: strconv.Quote(b.EpgLink), Err: err}
+		}
 		bc := broadcast{
 			BroadcastURL: r.BroadcastURL{
 				TimeURL: r.TimeURL{
-					Source:  *day.Source.ResolveReference(r.MustParseURL(b.EpgLink)),
+					Source:  *day.Source.ResolveReference(epg),
 					Time:    time.Unix(b.Start/1000, 0).In(day.Station.TimeZone),
 					Station: day.Station,
 				},
@@ -167,16 +185,17 @@ func (day *timeURL) parseBroadcastsFromJsonReader(read io.Reader, cr0 *r.Countin
 	err = json.NewDecoder(cr).Decode(&f)
 	r.ReportLoad("🐦", cr0, cr, day.Source)
 	if nil != err {
-		return
+		return nil, &r.ParseError{URL: day.Source, Err: err}
 	}
 	return day.parseBroadcastsFromJsonData(f)
 }
 
-func (day *timeURL) parseBroadcastsFromJsonURL() (ret []*broadcast, err error) {
-	bo, cr, err := r.HttpGetBody(day.Source)
+func (day *timeURL) parseBroadcastsFromJsonURL(ctx context.Context) (ret []*broadcast, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, day.Source)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return day.parseBroadcastsFromJsonReader(bo, cr)
 }
 
@@ -200,8 +219,8 @@ func (bc *broadcast) Matches(nows []time.Time) (ok bool) {
 	return false
 }
 
-func (bc broadcast) Scrape() (jobs []r.Scraper, results []r.Broadcaster, err error) {
-	bcs, err := bc.parseBroadcastFromHtmlURL()
+func (bc broadcast) Scrape(ctx context.Context) (jobs []r.Scraper, results []r.Broadcaster, err error) {
+	bcs, err := bc.parseBroadcastFromHtmlURL(ctx)
 	if nil == err {
 		for _, bc := range bcs {
 			results = append(results, bc)
@@ -270,7 +289,8 @@ func (bc *broadcast) parseBroadcastFromHtmlNode(root *html.Node) (ret []*r.Broad
 			bc.Description = &description
 		}
 	}
-	bc2 := r.Broadcast(*bc)
+	// schema.org JSON-LD, where given, beats the above
+	bc2 := r.PreferJSONLD(root, r.Broadcast(*bc), bc.Time)
 	ret = append(ret, &bc2)
 	return
 }
@@ -285,10 +305,11 @@ func (bc *broadcast) parseBroadcastFromHtmlReader(read io.Reader, cr0 *r.Countin
 	return bc.parseBroadcastFromHtmlNode(root)
 }
 
-func (bc *broadcast) parseBroadcastFromHtmlURL() (ret []*r.Broadcast, err error) {
-	bo, cr, err := r.HttpGetBody(bc.Source)
+func (bc *broadcast) parseBroadcastFromHtmlURL(ctx context.Context) (ret []*r.Broadcast, err error) {
+	bo, cr, err := r.HttpGetBody(ctx, bc.Source)
 	if nil == bo {
 		return nil, err
 	}
+	defer bo.Close()
 	return bc.parseBroadcastFromHtmlReader(bo, cr)
 }
@@ -23,7 +23,9 @@
 package wdr // import "purl.mro.name/recorder/radio/scrape/wdr"
 
 import (
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,6 +38,17 @@ func TestTimeZone(t *testing.T) {
 	assert.Equal(t, "Europe/Berlin", wdr.TimeZone.String(), "foo")
 }
 
+// Scrape() all the way down, offline, see testdata/fixtures/index.txt
+func TestScrapeFixtures(t *testing.T) {
+	bcs, err := r.CrawlFixtures("testdata/fixtures", Station("wdr5"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 3, len(bcs), "ouch")
+	first := bcs[0]
+	assert.Equal(t, "wdr5", first.Station.Identifier, "ouch")
+	assert.Equal(t, "WDR 5 - Tagesgespräch", first.Title, "ouch")
+	assert.Equal(t, "2016-07-25T12:10:00+02:00", first.Time.Format(time.RFC3339), "ouch")
+}
+
 func TestUnmarshalBroadcastsFromJSON(t *testing.T) {
 	f, err := os.Open("testdata/2016-07-25T1207-program.json")
 	assert.NotNil(t, f, "ouch")
@@ -111,6 +124,43 @@ func TestUnmarshalBroadcastFromHTML_0(t *testing.T) {
 	assert.Nil(t, bc.Image, "ouch: Image")
 }
 
+// schema.org JSON-LD, where given, beats the HTML
+func TestUnmarshalBroadcastFromHTMLJSONLD(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/2016-07-23T1705-sendung.html")
+	assert.Nil(t, err, "ouch")
+	ld := `<script type="application/ld+json">[{"@context": "http://schema.org", "@type": "BroadcastEvent",
+	"startDate": "2016-07-24T14:05:00+02:00", "name": "Krimi am Samstag (Wiederholung)"},
+	{"@context": "http://schema.org", "@type": "BroadcastEvent",
+	"startDate": "2016-07-23T17:05:00+02:00", "endDate": "2016-07-23T18:00:00+02:00",
+	"workPerformed": {"@type": "RadioEpisode", "name": "Krimi am Samstag", "image": "/bilder/knochenmann.jpg",
+	"author": {"@type": "Person", "name": "Wolf Haas"}, "creator": {"@type": "Organization", "name": "ORF/MDR"}}}]</script></head>`
+	htm := strings.Replace(string(data), "</head>", ld, 1)
+
+	s := Station("wdr5")
+	bc0 := broadcast(r.Broadcast{
+		BroadcastURL: r.BroadcastURL{
+			TimeURL: r.TimeURL{
+				Time:    time.Date(2016, time.July, 23, 17, 5, 0, 0, s.TimeZone),
+				Source:  *r.MustParseURL("http://www.wdr.de/programmvorschau/wdr5/sendung/2016-07-23/40920025/krimi-am-samstag.html"),
+				Station: r.Station(*s),
+			},
+			Title: "Krimi am Samstag",
+		},
+	})
+	res, err := bc0.parseBroadcastFromHtmlReader(strings.NewReader(htm), nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(res), "not the repeat")
+	bc := res[0]
+	assert.Equal(t, "Krimi am Samstag", bc.Title, "ouch: Title")
+	assert.Equal(t, "2016-07-23T17:05:00+02:00", bc.Time.Format(time.RFC3339), "ouch: Time")
+	assert.Equal(t, "Der Knochenmann", *bc.TitleEpisode, "HTML")
+	assert.Equal(t, "2016-07-23T18:00:00+02:00", bc.DtEnd.Format(time.RFC3339), "JSON-LD")
+	assert.Equal(t, "http://www.wdr.de/bilder/knochenmann.jpg", bc.Image.String(), "JSON-LD")
+	assert.Equal(t, "Wolf Haas", *bc.Author, "JSON-LD")
+	assert.Equal(t, "ORF/MDR", *bc.Creator, "JSON-LD")
+	assert.Equal(t, "http://www1.wdr.de/radio/wdr5/sendungen/krimi-am-samstag/uebersicht-krimi-am-samstag100.html", bc.Subject.String(), "HTML")
+}
+
 func TestUnmarshalBroadcastFromHTML_1(t *testing.T) {
 	f, err := os.Open("testdata/2016-08-21T1705-sendung.html")
 	assert.NotNil(t, f, "ouch")
@@ -0,0 +1,257 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+package xmltv
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Read
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// Honours the encoding of the xml declaration, e.g. ISO-8859-1 from older grabbers.
+func Read(r io.Reader) (ret TV, err error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	err = dec.Decode(&ret)
+	return
+}
+
+// XMLTV allows to drop trailing parts of the timestamp and the zone, which
+// then is loc.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	digits, zone := s, ""
+	if i := strings.IndexAny(s, " +-"); 0 <= i {
+		digits, zone = s[:i], strings.TrimSpace(s[i:])
+	}
+	layout := "20060102150405"
+	if len(digits) < 8 || len(digits) > len(layout) || 0 != len(digits)%2 {
+		return time.Time{}, errors.New("invalid XMLTV time '" + s + "'")
+	}
+	layout = layout[:len(digits)]
+	if "" == zone {
+		return time.ParseInLocation(layout, digits, loc)
+	}
+	return time.Parse(layout+" -0700", digits+" "+zone)
+}
+
+// The text in lang, else the first one.
+func pick(ts []Text, lang string) *Text {
+	for i := range ts {
+		if lang == ts[i].Lang {
+			return &ts[i]
+		}
+	}
+	if 0 < len(ts) {
+		return &ts[0]
+	}
+	return nil
+}
+
+// Map p to a Broadcast of st. Source is the first url, else base.
+func (p Programme) Broadcast(st scrape.Station, base url.URL) (bc scrape.Broadcast, err error) {
+	bc.Station = st
+	loc := st.TimeZone
+	if nil == loc {
+		loc = time.UTC
+	}
+	if bc.Time, err = ParseTime(p.Start, loc); nil != err {
+		return
+	}
+	bc.Time = bc.Time.In(loc)
+	if "" != p.Stop {
+		t, e := ParseTime(p.Stop, loc)
+		if nil != e {
+			return bc, e
+		}
+		t = t.In(loc)
+		bc.DtEnd = &t
+	}
+	lang := ""
+	if nil != p.Language {
+		lang = p.Language.Value
+	} else if 0 < len(p.Title) {
+		lang = p.Title[0].Lang
+	}
+	if "" != lang {
+		bc.Language = &lang
+	}
+	if t := pick(p.Title, lang); nil != t {
+		bc.Title = t.Value
+	}
+	if t := pick(p.SubTitle, lang); nil != t {
+		bc.TitleEpisode = &t.Value
+	}
+	if t := pick(p.Desc, lang); nil != t {
+		bc.Description = &t.Value
+	}
+	if nil != p.Credits && 0 < len(p.Credits.Presenter) {
+		author := strings.Join(p.Credits.Presenter, ", ")
+		bc.Author = &author
+	}
+	if 0 < len(p.Icon) {
+		if bc.Image, err = url.Parse(p.Icon[0].Src); nil != err {
+			return
+		}
+	}
+	bc.Source = base
+	if 0 < len(p.URL) {
+		u, e := url.Parse(strings.TrimSpace(p.URL[0]))
+		if nil != e {
+			return bc, e
+		}
+		bc.Source = *base.ResolveReference(u)
+	}
+	if "" != st.Name {
+		bc.Publisher = &st.Name
+	}
+	return
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// A station configured by data only
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// htdocs/stations/<id>/app/station.cfg like
+//
+//	{
+//		title = 'Radio X',
+//		program_url = 'https://radio-x.example/programm/',
+//		scrape_source = 'xmltv',
+//		scrape_url = 'https://epg.example/tv.xml', -- or file:///var/lib/epg/tv.xml
+//		xmltv_channel = 'radiox.example', -- the channel id in there, default: the station id
+//		day_start = '0500',
+//		timezone = 'Europe/Berlin',
+//	}
+func init() {
+	scrape.RegisterSource("xmltv", func(st scrape.Station) scrape.Scraper { s := station(st); return &s })
+}
+
+type station scrape.Station
+
+func (s *station) String() string {
+	return "xmltv " + s.Identifier + " " + s.ProgramURL.String()
+}
+
+// The channel id of the station in the XMLTV file.
+func (s *station) channel() string {
+	if c := s.Params["xmltv_channel"]; "" != c {
+		return c
+	}
+	return s.Identifier
+}
+
+func (s *station) Matches(nows []time.Time) (ok bool) {
+	return true
+}
+
+// Read the whole file, it's all there is, and hand it out as one job per
+// broadcast day.
+func (s *station) Scrape(ctx context.Context) (jobs []scrape.Scraper, results []scrape.Broadcaster, err error) {
+	var bo io.ReadCloser
+	var cr0 *scrape.CountingReader
+	if "file" == s.ProgramURL.Scheme {
+		bo, err = os.Open(s.ProgramURL.Path)
+	} else {
+		bo, cr0, err = scrape.HttpGetBody(ctx, *s.ProgramURL)
+	}
+	if nil != err {
+		return
+	}
+	defer bo.Close()
+	cr := scrape.NewCountingReader(bo)
+	tv, err := Read(cr)
+	scrape.ReportLoad("🐦", cr0, cr, *s.ProgramURL)
+	if nil != err {
+		return nil, nil, &scrape.ParseError{URL: *s.ProgramURL, Err: err}
+	}
+	st := scrape.Station(*s)
+	days := make(map[time.Time]*day)
+	for _, p := range tv.Programmes {
+		if s.channel() != p.Channel {
+			continue
+		}
+		bc, err := p.Broadcast(st, *s.ProgramURL)
+		if nil != err {
+			return nil, nil, &scrape.ParseError{URL: *s.ProgramURL, Node: p.Channel + " " + p.Start, Err: err}
+		}
+		t0 := dayOf(st, bc.Time)
+		d, ok := days[t0]
+		if !ok {
+			d = &day{TimeURL: scrape.TimeURL{Time: t0, Source: *s.ProgramURL, Station: st}}
+			days[t0] = d
+			jobs = append(jobs, d)
+		}
+		d.broadcasts = append(d.broadcasts, bc)
+	}
+	return
+}
+
+// The broadcast day containing t, at midnight, see Station.DayStart.
+func dayOf(st scrape.Station, t time.Time) time.Time {
+	t = st.DayStart(t)
+	if nil != st.TimeZone {
+		t = t.In(st.TimeZone)
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// The programmes of one broadcast day, already read
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type day struct {
+	scrape.TimeURL
+	broadcasts []scrape.Broadcast
+}
+
+func (d *day) String() string {
+	return "xmltv " + d.Station.Identifier + " " + d.Time.Format("2006-01-02")
+}
+
+// Is one of nows on this day?
+func (d *day) Matches(nows []time.Time) (ok bool) {
+	for _, now := range nows {
+		if d.Time.Equal(dayOf(d.Station, now)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *day) Scrape(ctx context.Context) (jobs []scrape.Scraper, results []scrape.Broadcaster, err error) {
+	for _, bc := range d.broadcasts {
+		results = append(results, bc)
+	}
+	return
+}
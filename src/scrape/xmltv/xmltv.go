@@ -0,0 +1,198 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// Write scrape.Broadcasts as XMLTV, the EPG format of media centers like
+// Kodi, Jellyfin or TVHeadend, see http://wiki.xmltv.org/index.php/XMLTVFormat
+// and https://github.com/XMLTV/xmltv/blob/master/xmltv.dtd
+//
+// import "purl.mro.name/recorder/radio/scrape/xmltv"
+
+package xmltv
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"time"
+
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+const (
+	DocType = `<!DOCTYPE tv SYSTEM "xmltv.dtd">`
+	// start and stop of a programme
+	TimeFormat = "20060102150405 -0700"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// The elements, in the order the DTD wants them
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type Text struct {
+	Lang  string `xml:"lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type Icon struct {
+	Src string `xml:"src,attr"`
+}
+
+type Channel struct {
+	ID          string   `xml:"id,attr"`
+	DisplayName []Text   `xml:"display-name"`
+	Icon        []Icon   `xml:"icon"`
+	URL         []string `xml:"url"`
+}
+
+// Only the people this package reads.
+type Credits struct {
+	Director  []string `xml:"director"`
+	Actor     []string `xml:"actor"`
+	Writer    []string `xml:"writer"`
+	Presenter []string `xml:"presenter"`
+	Guest     []string `xml:"guest"`
+}
+
+type Programme struct {
+	Start    string   `xml:"start,attr"`
+	Stop     string   `xml:"stop,attr,omitempty"`
+	Channel  string   `xml:"channel,attr"`
+	Title    []Text   `xml:"title"`
+	SubTitle []Text   `xml:"sub-title"`
+	Desc     []Text   `xml:"desc"`
+	Credits  *Credits `xml:"credits"`
+	Category []Text   `xml:"category"`
+	Language *Text    `xml:"language"`
+	Icon     []Icon   `xml:"icon"`
+	URL      []string `xml:"url"`
+}
+
+type TV struct {
+	XMLName           xml.Name    `xml:"tv"`
+	GeneratorInfoName string      `xml:"generator-info-name,attr,omitempty"`
+	GeneratorInfoURL  string      `xml:"generator-info-url,attr,omitempty"`
+	Channels          []Channel   `xml:"channel"`
+	Programmes        []Programme `xml:"programme"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// From scrape
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// The XMLTV channel id of a station, its Identifier.
+func ChannelID(st scrape.Station) string {
+	return st.Identifier
+}
+
+func NewChannel(st scrape.Station) (ret Channel) {
+	ret.ID = ChannelID(st)
+	name := st.Name
+	if "" == name {
+		name = st.Identifier
+	}
+	ret.DisplayName = []Text{{Value: name}}
+	if nil != st.Logo {
+		ret.Icon = []Icon{{Src: st.Logo.String()}}
+	}
+	if nil != st.Homepage {
+		ret.URL = []string{st.Homepage.String()}
+	}
+	return
+}
+
+// start and stop in the station's TimeZone, if known.
+func NewProgramme(bc scrape.Broadcast) (ret Programme) {
+	local := func(t time.Time) string {
+		if nil != bc.Station.TimeZone {
+			t = t.In(bc.Station.TimeZone)
+		}
+		return t.Format(TimeFormat)
+	}
+	lang := ""
+	if nil != bc.Language {
+		lang = *bc.Language
+	}
+	ret.Start = local(bc.Time)
+	if nil != bc.DtEnd {
+		ret.Stop = local(*bc.DtEnd)
+	}
+	ret.Channel = ChannelID(bc.Station)
+	ret.Title = []Text{{Lang: lang, Value: bc.Title}}
+	if nil != bc.TitleEpisode && "" != *bc.TitleEpisode {
+		ret.SubTitle = []Text{{Lang: lang, Value: *bc.TitleEpisode}}
+	}
+	if nil != bc.Description && "" != *bc.Description {
+		ret.Desc = []Text{{Lang: lang, Value: *bc.Description}}
+	}
+	if "" != lang {
+		ret.Language = &Text{Value: lang}
+	}
+	if nil != bc.Image {
+		ret.Icon = []Icon{{Src: bc.Image.String()}}
+	}
+	if "" != bc.Source.String() {
+		ret.URL = []string{bc.Source.String()}
+	}
+	return
+}
+
+// Collects broadcasts into a complete XMLTV document, a channel per station.
+type Document struct {
+	TV
+	channels map[string]bool
+}
+
+func NewDocument() *Document {
+	return &Document{
+		TV:       TV{GeneratorInfoName: "scrape-cmd", GeneratorInfoURL: "http://purl.mro.name/recorder"},
+		channels: make(map[string]bool),
+	}
+}
+
+func (d *Document) Add(bc scrape.Broadcast) {
+	if id := ChannelID(bc.Station); !d.channels[id] {
+		d.channels[id] = true
+		d.Channels = append(d.Channels, NewChannel(bc.Station))
+	}
+	d.Programmes = append(d.Programmes, NewProgramme(bc))
+}
+
+// Channels by id, then their programmes by start.
+func (d *Document) Write(w io.Writer) (err error) {
+	sort.SliceStable(d.Channels, func(a, b int) bool { return d.Channels[a].ID < d.Channels[b].ID })
+	sort.SliceStable(d.Programmes, func(a, b int) bool {
+		pa, pb := d.Programmes[a], d.Programmes[b]
+		if pa.Channel != pb.Channel {
+			return pa.Channel < pb.Channel
+		}
+		ta, _ := time.Parse(TimeFormat, pa.Start)
+		tb, _ := time.Parse(TimeFormat, pb.Start)
+		return ta.Before(tb)
+	})
+	if _, err = io.WriteString(w, xml.Header+DocType+"\n"); nil != err {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(d.TV); nil != err {
+		return
+	}
+	_, err = io.WriteString(w, "\n")
+	return
+}
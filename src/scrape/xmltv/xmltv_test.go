@@ -0,0 +1,356 @@
+// Copyright (c) 2015-2017 Marcus Rohrmoser, http://purl.mro.name/recorder
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute,
+// sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or
+// substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+// NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+// OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+// MIT License http://opensource.org/licenses/MIT
+
+// http://golang.org/pkg/testing/
+// http://blog.stretchr.com/2014/03/05/test-driven-development-specifically-in-golang/
+//
+// import "purl.mro.name/recorder/radio/scrape/xmltv"
+//
+package xmltv
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"purl.mro.name/recorder/radio/scrape"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////
+/// Just enough of a DTD validator for testdata/xmltv.dtd
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type dtd struct {
+	models map[string]*regexp.Regexp // children as "name name ", nil: #PCDATA
+	empty  map[string]bool
+	attrs  map[string]map[string]bool // name -> required?
+}
+
+var (
+	rxDtdElement = regexp.MustCompile(`<!ELEMENT\s+(\S+)\s+([^>]*)>`)
+	rxDtdAttlist = regexp.MustCompile(`<!ATTLIST\s+(\S+)\s+([^>]*)>`)
+	rxDtdAttr    = regexp.MustCompile(`(\S+)\s+CDATA\s+(#REQUIRED|#IMPLIED|"[^"]*")`)
+	rxDtdName    = regexp.MustCompile(`[a-z][a-z-]*`)
+)
+
+func loadDtd(file string) (ret dtd, err error) {
+	data, err := ioutil.ReadFile(file)
+	if nil != err {
+		return
+	}
+	ret = dtd{models: map[string]*regexp.Regexp{}, empty: map[string]bool{}, attrs: map[string]map[string]bool{}}
+	for _, m := range rxDtdElement.FindAllStringSubmatch(string(data), -1) {
+		model := strings.Join(strings.Fields(m[2]), "")
+		switch model {
+		case "EMPTY":
+			ret.empty[m[1]] = true
+		case "(#PCDATA)":
+			ret.models[m[1]] = nil
+		default:
+			rx := rxDtdName.ReplaceAllString(strings.Replace(model, ",", "", -1), "(?:$0 )")
+			ret.models[m[1]] = regexp.MustCompile("^" + rx + "$")
+		}
+	}
+	for _, m := range rxDtdAttlist.FindAllStringSubmatch(string(data), -1) {
+		as := map[string]bool{}
+		for _, a := range rxDtdAttr.FindAllStringSubmatch(m[2], -1) {
+			as[a[1]] = "#REQUIRED" == a[2]
+		}
+		ret.attrs[m[1]] = as
+	}
+	return
+}
+
+// Check the element starting with start and its descendants.
+func (d dtd) validate(dec *xml.Decoder, start xml.StartElement) (problems []string) {
+	name := start.Name.Local
+	model, known := d.models[name]
+	if !known && !d.empty[name] {
+		return []string{"undeclared <" + name + ">"}
+	}
+	seen := map[string]bool{}
+	for _, a := range start.Attr {
+		seen[a.Name.Local] = true
+		if _, ok := d.attrs[name][a.Name.Local]; !ok {
+			problems = append(problems, fmt.Sprintf("undeclared %s/@%s", name, a.Name.Local))
+		}
+	}
+	for a, required := range d.attrs[name] {
+		if required && !seen[a] {
+			problems = append(problems, fmt.Sprintf("missing %s/@%s", name, a))
+		}
+	}
+	children := ""
+	text := false
+	for {
+		tok, err := dec.Token()
+		if nil != err {
+			return append(problems, err.Error())
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			children += t.Name.Local + " "
+			problems = append(problems, d.validate(dec, t)...)
+		case xml.CharData:
+			text = text || "" != strings.TrimSpace(string(t))
+		case xml.EndElement:
+			switch {
+			case d.empty[name] && ("" != children || text):
+				problems = append(problems, "<"+name+"> not empty")
+			case !d.empty[name] && nil == model && "" != children:
+				problems = append(problems, "<"+name+"> has children")
+			case nil != model && text:
+				problems = append(problems, "<"+name+"> has text")
+			case nil != model && !model.MatchString(children):
+				problems = append(problems, fmt.Sprintf("<%s> children don't match: %s", name, children))
+			}
+			return
+		}
+	}
+}
+
+func validate(d dtd, r io.Reader) (problems []string) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if io.EOF == err {
+			return
+		}
+		if nil != err {
+			return append(problems, err.Error())
+		}
+		if t, ok := tok.(xml.StartElement); ok {
+			return d.validate(dec, t)
+		}
+	}
+}
+
+func TestDtdValidator(t *testing.T) {
+	d, err := loadDtd("testdata/xmltv.dtd")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 0, len(validate(d, strings.NewReader(`<tv><channel id="a"><display-name>A</display-name></channel></tv>`))), "ouch")
+	assert.Equal(t, []string{
+		"missing programme/@channel",
+		"<programme> children don't match: desc title ",
+		"<tv> children don't match: programme channel ",
+	}, validate(d, strings.NewReader(`<tv><programme start="x"><desc>D</desc><title>T</title></programme><channel id="a"><display-name>A</display-name></channel></tv>`)), "ouch")
+	assert.Equal(t, []string{"undeclared icon/@href", "missing icon/@src"}, validate(d, strings.NewReader(`<icon href="x"/>`)), "ouch")
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+
+func TestDocument(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	st := scrape.Station{Identifier: "b2", Name: "Bayern 2", TimeZone: berlin, Logo: scrape.MustParseURL("http://www.br.de/logo.png"), Homepage: scrape.MustParseURL("http://www.br.de/radio/bayern2/")}
+	bc := func(start time.Time, title string) scrape.Broadcast {
+		b := scrape.Broadcast{}
+		b.Station = st
+		b.Time = start
+		b.Title = title
+		b.Source = *scrape.MustParseURL("http://www.br.de/radio/bayern2/sendungen/x.html")
+		return b
+	}
+	de, episode, desc := "de", "anspruchsvoll - entspannt - weltoffen", "Mit Riegler Hias & Rebekka Bakken"
+	t0 := time.Date(2016, time.August, 25, 16, 5, 0, 0, time.UTC)
+	t1 := t0.Add(25 * time.Minute)
+	b0 := bc(t0, "Bayern 2-radioMusik")
+	b0.DtEnd, b0.Language, b0.TitleEpisode, b0.Description = &t1, &de, &episode, &desc
+	b0.Image = scrape.MustParseURL("http://www.br.de/img.jpg")
+	b1 := bc(t1, "Nachrichten")
+	other := bc(t0, "Elsewhere")
+	other.Station = scrape.Station{Identifier: "a1"}
+
+	d := NewDocument()
+	d.Add(b1)
+	d.Add(b0)
+	d.Add(other)
+	var buf bytes.Buffer
+	assert.Nil(t, d.Write(&buf), "ouch")
+
+	dt, err := loadDtd("testdata/xmltv.dtd")
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 0, len(validate(dt, bytes.NewReader(buf.Bytes()))), buf.String())
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE tv SYSTEM "xmltv.dtd">
+<tv generator-info-name="scrape-cmd" generator-info-url="http://purl.mro.name/recorder">
+  <channel id="a1">
+    <display-name>a1</display-name>
+  </channel>
+  <channel id="b2">
+    <display-name>Bayern 2</display-name>
+    <icon src="http://www.br.de/logo.png"></icon>
+    <url>http://www.br.de/radio/bayern2/</url>
+  </channel>
+  <programme start="20160825160500 +0000" channel="a1">
+    <title>Elsewhere</title>
+    <url>http://www.br.de/radio/bayern2/sendungen/x.html</url>
+  </programme>
+  <programme start="20160825180500 +0200" stop="20160825183000 +0200" channel="b2">
+    <title lang="de">Bayern 2-radioMusik</title>
+    <sub-title lang="de">anspruchsvoll - entspannt - weltoffen</sub-title>
+    <desc lang="de">Mit Riegler Hias &amp; Rebekka Bakken</desc>
+    <language>de</language>
+    <icon src="http://www.br.de/img.jpg"></icon>
+    <url>http://www.br.de/radio/bayern2/sendungen/x.html</url>
+  </programme>
+  <programme start="20160825183000 +0200" channel="b2">
+    <title>Nachrichten</title>
+    <url>http://www.br.de/radio/bayern2/sendungen/x.html</url>
+  </programme>
+</tv>
+`, buf.String(), "ouch")
+}
+
+func TestParseTime(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	for s, expected := range map[string]string{
+		"20161030013000 +0200": "2016-10-30T01:30:00+02:00",
+		"20161030023000 +0100": "2016-10-30T02:30:00+01:00",
+		"201610300130 +0200":   "2016-10-30T01:30:00+02:00",
+		"20161030120000":       "2016-10-30T12:00:00+01:00",
+		"20160725":             "2016-07-25T00:00:00+02:00",
+	} {
+		tt, err := ParseTime(s, berlin)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, tt.Format(time.RFC3339), s)
+	}
+	for _, s := range []string{"", "2016", "2016103001300", "20161030013000 CEST"} {
+		_, err := ParseTime(s, berlin)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestReadProgrammes(t *testing.T) {
+	f, err := os.Open("testdata/tv.xml")
+	assert.Nil(t, err, "ouch")
+	defer f.Close()
+	tv, err := Read(f)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(tv.Channels), "ouch")
+	assert.Equal(t, 3, len(tv.Programmes), "ouch")
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	st := scrape.Station{Identifier: "radio-x", Name: "Radio X", TimeZone: berlin}
+	bc, err := tv.Programmes[0].Broadcast(st, *scrape.MustParseURL("https://radio-x.example/programm/"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "radio-x", bc.Station.Identifier, "ouch")
+	assert.Equal(t, "2016-10-30T01:30:00+02:00", bc.Time.Format(time.RFC3339), "ouch")
+	assert.Equal(t, "2016-10-30T02:00:00+01:00", bc.DtEnd.Format(time.RFC3339), "ouch")
+	assert.Equal(t, 90*time.Minute, bc.DtEnd.Sub(bc.Time), "DST ends")
+	assert.Equal(t, "Nachtschicht", bc.Title, "ouch")
+	assert.Equal(t, "de", *bc.Language, "ouch")
+	assert.Equal(t, "Die Stunde, die es zweimal gibt", *bc.TitleEpisode, "ouch")
+	assert.Equal(t, "Zur Zeitumstellung", *bc.Description, "the language of the title")
+	assert.Equal(t, "Anna, Bernd", *bc.Author, "ouch")
+	assert.Equal(t, "Radio X", *bc.Publisher, "ouch")
+	assert.Equal(t, "https://radio-x.example/img/nacht.jpg", bc.Image.String(), "ouch")
+	assert.Equal(t, "https://radio-x.example/sendungen/nachtschicht.html", bc.Source.String(), "ouch")
+
+	bc, err = tv.Programmes[1].Broadcast(st, *scrape.MustParseURL("https://radio-x.example/programm/"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "2016-10-30T06:00:00+01:00", bc.Time.Format(time.RFC3339), "no seconds, no zone")
+	assert.Nil(t, bc.DtEnd, "ouch")
+	assert.Nil(t, bc.Language, "ouch")
+	assert.Equal(t, "https://radio-x.example/programm/", bc.Source.String(), "ouch")
+}
+
+func TestRoundTrip(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	st := scrape.Station{Identifier: "b2", Name: "Bayern 2", TimeZone: berlin}
+	b0 := scrape.Broadcast{}
+	b0.Station = st
+	b0.Title = "Bayern 2-radioMusik"
+	b0.Time = time.Date(2016, time.August, 25, 18, 5, 0, 0, berlin)
+	end, de, ep := b0.Time.Add(25*time.Minute), "de", "anspruchsvoll"
+	b0.DtEnd, b0.Language, b0.TitleEpisode, b0.Description = &end, &de, &ep, &ep
+	b0.Source = *scrape.MustParseURL("http://www.br.de/x.html")
+	b0.Image = scrape.MustParseURL("http://www.br.de/img.jpg")
+
+	d := NewDocument()
+	d.Add(b0)
+	var buf bytes.Buffer
+	assert.Nil(t, d.Write(&buf), "ouch")
+	tv, err := Read(&buf)
+	assert.Nil(t, err, "ouch")
+	b1, err := tv.Programmes[0].Broadcast(st, url0)
+	assert.Nil(t, err, "ouch")
+	b0.Publisher = &st.Name
+	assert.Equal(t, b0, b1, "ouch")
+}
+
+var url0 = *scrape.MustParseURL("http://example.com/")
+
+// LoadStations() registers radio-x, Scrape() it offline, see testdata/fixtures/index.txt
+func TestSource(t *testing.T) {
+	defer func(c *http.Client) { scrape.Client = c }(scrape.Client)
+	scrape.Client = &http.Client{Transport: &scrape.Fixtures{Dir: "testdata/fixtures"}}
+
+	assert.Nil(t, scrape.LoadStations("testdata/stations"), "ouch")
+	s, err := scrape.NewScraper("radio-x")
+	assert.Nil(t, err, "ouch")
+	recorded := time.Date(2016, time.October, 29, 12, 0, 0, 0, time.UTC)
+	bcs, err := scrape.Crawl(context.Background(), s, []time.Time{recorded, recorded.Add(24 * time.Hour)})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 2, len(bcs), "other.example isn't ours")
+	bc, _ := scrape.AsBroadcast(bcs[0])
+	assert.Equal(t, "radio-x", bc.Station.Identifier, "ouch")
+	assert.Equal(t, "Nachtschicht", bc.Title, "ouch")
+	assert.Equal(t, "Europe/Berlin", bc.Time.Location().String(), "ouch")
+	bc, _ = scrape.AsBroadcast(bcs[1])
+	assert.Equal(t, "Frühschicht", bc.Title, "ouch")
+
+	bcs, err = scrape.Crawl(context.Background(), s, []time.Time{recorded})
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(bcs), "01:30 belongs to the day before, day_start is 0500")
+	bc, _ = scrape.AsBroadcast(bcs[0])
+	assert.Equal(t, "Nachtschicht", bc.Title, "ouch")
+
+	bcs, err = scrape.Crawl(context.Background(), s, nil)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 0, len(bcs), "no day due")
+
+	assert.Nil(t, scrape.LoadStations("testdata/stations"), "again just updates")
+}
+
+func TestReadLatin1(t *testing.T) {
+	f, err := os.Open("testdata/tv-latin1.xml")
+	assert.Nil(t, err, "ouch")
+	defer f.Close()
+	tv, err := Read(f)
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, 1, len(tv.Programmes), "ouch")
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	st := scrape.Station{Identifier: "radio-x", Name: "Radio X", TimeZone: berlin}
+	bc, err := tv.Programmes[0].Broadcast(st, *scrape.MustParseURL("https://radio-x.example/programm/"))
+	assert.Nil(t, err, "ouch")
+	assert.Equal(t, "Frühschicht", bc.Title, "ouch")
+	assert.Equal(t, "Grüße aus Köln", *bc.Description, "ouch")
+}